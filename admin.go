@@ -0,0 +1,222 @@
+package mongo
+
+import (
+	"errors"
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+// RunCommand 在指定数据库上执行任意命令
+func (c *Client) RunCommand(database string, cmd M, result interface{}) (err error) {
+	defer c.observe(&err, "RunCommand", database, "", time.Now())
+	if err := c.ready(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	return c.withRetry(session, func() error {
+		return session.DB(database).Run(cmd, result)
+	})
+}
+
+// CollectionNames 返回数据库中的所有集合名称
+func (c *Client) CollectionNames(database string) (names []string, err error) {
+	defer c.observe(&err, "CollectionNames", database, "", time.Now())
+	if err := c.ready(); err != nil {
+		return nil, err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	err = c.withRetry(session, func() error {
+		var err error
+		names, err = session.DB(database).CollectionNames()
+		return err
+	})
+	return names, err
+}
+
+// DatabaseNames 返回服务器上的所有数据库名称
+func (c *Client) DatabaseNames() (names []string, err error) {
+	defer c.observe(&err, "DatabaseNames", "", "", time.Now())
+	if err := c.ready(); err != nil {
+		return nil, err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	err = c.withRetry(session, func() error {
+		var err error
+		names, err = session.DatabaseNames()
+		return err
+	})
+	return names, err
+}
+
+// CollectionInfo 创建集合选项
+type CollectionInfo struct {
+	Capped    bool //固定大小集合,必须同时设置MaxBytes
+	MaxBytes  int  //固定大小集合的最大字节数
+	MaxDocs   int  //固定大小集合的最大数据条数
+	Validator M    //数据校验规则,如M{"$jsonSchema": ...}
+}
+
+// CreateCollection 按选项创建集合,可用于创建固定大小集合和带校验规则的集合
+func (c *Client) CreateCollection(database, collection string, info CollectionInfo) (err error) {
+	defer c.observe(&err, "CreateCollection", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	createInfo := &mgo.CollectionInfo{
+		Capped:   info.Capped,
+		MaxBytes: info.MaxBytes,
+		MaxDocs:  info.MaxDocs,
+	}
+	if info.Validator != nil {
+		createInfo.Validator = info.Validator
+	}
+	return c.withRetry(session, func() error {
+		return conn.Create(createInfo)
+	})
+}
+
+// DropCollection 删除集合,集合不存在时返回nil
+func (c *Client) DropCollection(database, collection string) (err error) {
+	defer c.observe(&err, "DropCollection", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	err = c.withRetry(session, func() error {
+		return session.DB(database).C(collection).DropCollection()
+	})
+	if isNamespaceNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// DropDatabase 删除数据库,数据库不存在时返回nil
+func (c *Client) DropDatabase(database string) (err error) {
+	defer c.observe(&err, "DropDatabase", database, "", time.Now())
+	if err := c.ready(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	return c.withRetry(session, func() error {
+		return session.DB(database).DropDatabase()
+	})
+}
+
+// isNamespaceNotFound 返回错误是否为集合不存在
+func isNamespaceNotFound(err error) bool {
+	if qerr, ok := err.(*mgo.QueryError); ok {
+		return qerr.Code == 26 || qerr.Message == "ns not found"
+	}
+	return false
+}
+
+// RenameCollection 在同一个数据库中重命名集合,dropTarget为true时会先删除已存在的目标集合
+func (c *Client) RenameCollection(database, from, to string, dropTarget bool) (err error) {
+	defer c.observe(&err, "RenameCollection", database, from, time.Now())
+	if err := c.ready(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	cmd := bson.D{
+		{Name: "renameCollection", Value: database + "." + from},
+		{Name: "to", Value: database + "." + to},
+		{Name: "dropTarget", Value: dropTarget},
+	}
+	return c.withRetry(session, func() error {
+		return session.DB("admin").Run(cmd, nil)
+	})
+}
+
+// ValidateCollection 执行validate命令检查集合数据和索引的完整性,返回结果中valid为false时表示有损坏
+// full为true时进行完整检查,耗时较长且会锁定集合
+func (c *Client) ValidateCollection(database, collection string, full bool) (result M, err error) {
+	defer c.observe(&err, "ValidateCollection", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return nil, err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	cmd := bson.D{
+		{Name: "validate", Value: collection},
+		{Name: "full", Value: full},
+	}
+	result = M{}
+	err = c.withRetry(session, func() error {
+		return session.DB(database).Run(cmd, &result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ClusterTime 返回集群当前的逻辑时间,可作为处理oplog或变更流的检查点,需要MongoDB 3.6及以上的副本集或分片集群
+// 变更流的恢复令牌通过Watch事件的ID获取
+func (c *Client) ClusterTime() (ts bson.MongoTimestamp, err error) {
+	defer c.observe(&err, "ClusterTime", "admin", "", time.Now())
+	if err := c.ready(); err != nil {
+		return 0, err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	var result struct {
+		ClusterTime struct {
+			ClusterTime bson.MongoTimestamp `bson:"clusterTime"`
+		} `bson:"$clusterTime"`
+		OperationTime bson.MongoTimestamp `bson:"operationTime"`
+	}
+	err = c.withRetry(session, func() error {
+		return session.DB("admin").Run(M{"isMaster": 1}, &result)
+	})
+	if err != nil {
+		return 0, err
+	}
+	if result.ClusterTime.ClusterTime != 0 {
+		return result.ClusterTime.ClusterTime, nil
+	}
+	if result.OperationTime != 0 {
+		return result.OperationTime, nil
+	}
+	return 0, errors.New("cluster time not available, requires a replica set or sharded cluster")
+}
+
+// BuildInfo 自定义服务器编译信息类型
+type BuildInfo = mgo.BuildInfo
+
+// ServerStatus 返回服务器运行状态,包括运行时间、连接数等
+func (c *Client) ServerStatus() (M, error) {
+	result := M{}
+	err := c.RunCommand("admin", M{"serverStatus": 1}, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// BuildInfo 返回服务器版本等编译信息
+func (c *Client) BuildInfo() (info BuildInfo, err error) {
+	defer c.observe(&err, "BuildInfo", "", "", time.Now())
+	if err := c.ready(); err != nil {
+		return BuildInfo{}, err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	err = c.withRetry(session, func() error {
+		var err error
+		info, err = session.BuildInfo()
+		return err
+	})
+	return info, err
+}
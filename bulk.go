@@ -0,0 +1,241 @@
+package mongo
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+// Bulk 批量操作构建器,调用Run时一次性提交
+type Bulk struct {
+	client     *Client
+	database   string
+	collection string
+	unordered  bool
+	ops        []func(bulk *mgo.Bulk)
+	err        error //添加操作时的错误,Run时返回
+}
+
+// BulkResult 批量操作结果
+type BulkResult struct {
+	Matched  int
+	Modified int
+	Upserted int //upsert新插入的条数,只有BulkUpsert会返回,Matched不包含这部分
+}
+
+// Bulk 返回集合的批量操作构建器
+func (c *Client) Bulk(database, collection string) *Bulk {
+	return &Bulk{client: c, database: database, collection: collection}
+}
+
+// Unordered 设置为无序执行,单条失败不影响其余操作
+func (b *Bulk) Unordered() *Bulk {
+	b.unordered = true
+	return b
+}
+
+// Insert 添加插入操作
+func (b *Bulk) Insert(docs ...interface{}) *Bulk {
+	b.ops = append(b.ops, func(bulk *mgo.Bulk) {
+		bulk.Insert(docs...)
+	})
+	return b
+}
+
+// Update 添加更新操作,pairs为selector和update交替排列
+// 开启SetSafeDeletes时selector为空会使Run返回ErrEmptySelector且不提交任何操作
+func (b *Bulk) Update(pairs ...M) *Bulk {
+	for i := 0; i < len(pairs); i += 2 {
+		b.check(pairs[i])
+	}
+	b.ops = append(b.ops, func(bulk *mgo.Bulk) {
+		bulk.Update(pairsToInterfaces(pairs)...)
+	})
+	return b
+}
+
+// Upsert 添加更新或插入操作,pairs为selector和update交替排列
+func (b *Bulk) Upsert(pairs ...M) *Bulk {
+	b.ops = append(b.ops, func(bulk *mgo.Bulk) {
+		bulk.Upsert(pairsToInterfaces(pairs)...)
+	})
+	return b
+}
+
+// Remove 添加删除操作,每个selector删除一条数据
+// 开启SetSafeDeletes时selector为空会使Run返回ErrEmptySelector且不提交任何操作
+func (b *Bulk) Remove(selectors ...M) *Bulk {
+	for _, selector := range selectors {
+		b.check(selector)
+	}
+	b.ops = append(b.ops, func(bulk *mgo.Bulk) {
+		bulk.Remove(pairsToInterfaces(selectors)...)
+	})
+	return b
+}
+
+// check 检查selector,记录第一个错误
+func (b *Bulk) check(selector M) {
+	if b.err == nil {
+		b.err = b.client.checkSelector(selector)
+	}
+}
+
+// Run 提交所有批量操作
+func (b *Bulk) Run() (result BulkResult, err error) {
+	defer b.client.observe(&err, "Bulk", b.database, b.collection, time.Now())
+	if err := b.client.ready(); err != nil {
+		return BulkResult{}, err
+	}
+	if b.err != nil {
+		return BulkResult{}, b.err
+	}
+	session := b.client.session.Copy()
+	defer session.Close()
+	conn := session.DB(b.database).C(b.collection)
+	var info *mgo.BulkResult
+	err = b.client.withWrite(session, func() error {
+		bulk := conn.Bulk()
+		if b.unordered {
+			bulk.Unordered()
+		}
+		for _, op := range b.ops {
+			op(bulk)
+		}
+		var err error
+		info, err = bulk.Run()
+		return err
+	})
+	if err == ErrOperationTimeout || info == nil {
+		return BulkResult{}, err
+	}
+	return BulkResult{Matched: info.Matched, Modified: info.Modified}, err
+}
+
+// BulkUpsert 以keyField为条件无序批量更新或插入docs,用于同步数据,keyField应有唯一索引
+// 数据中的_id只在插入时设置,其余字段通过$set更新,每1000条提交一次,出错时返回已提交批次的结果
+func (c *Client) BulkUpsert(database, collection, keyField string, docs []M) (result BulkResult, err error) {
+	defer c.observe(&err, "BulkUpsert", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return BulkResult{}, err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	updates := make([]M, len(docs))
+	for i, doc := range docs {
+		set := make(M, len(doc))
+		for key, value := range doc {
+			if key != "_id" {
+				set[key] = value
+			}
+		}
+		update := M{"$set": set}
+		if id, ok := doc["_id"]; ok {
+			update["$setOnInsert"] = M{"_id": id}
+		}
+		updates[i] = M{"q": M{keyField: doc[keyField]}, "u": update, "upsert": true}
+	}
+	for start := 0; start < len(updates); start += maxWriteBatch {
+		end := start + maxWriteBatch
+		if end > len(updates) {
+			end = len(updates)
+		}
+		var info updateResult
+		err = c.withWrite(session, func() error {
+			var err error
+			info, err = runUpdate(session, database, collection, updates[start:end], false)
+			return err
+		})
+		if err == ErrOperationTimeout {
+			return result, err
+		}
+		result.Matched += info.N - len(info.Upserted)
+		result.Modified += info.NModified
+		result.Upserted += len(info.Upserted)
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// maxWriteBatch 每条写命令包含的最大操作数,与MongoDB 3.6之前的限制一致
+const maxWriteBatch = 1000
+
+// updateResult update命令的结果
+type updateResult struct {
+	N         int `bson:"n"`
+	NModified int `bson:"nModified"`
+	Upserted  []struct {
+		Index int         `bson:"index"`
+		ID    interface{} `bson:"_id"`
+	} `bson:"upserted"`
+	WriteErrors []struct {
+		Code   int    `bson:"code"`
+		ErrMsg string `bson:"errmsg"`
+	} `bson:"writeErrors"`
+	WriteConcernError *struct {
+		Code   int    `bson:"code"`
+		ErrMsg string `bson:"errmsg"`
+	} `bson:"writeConcernError"`
+}
+
+// runUpdate 使用session的写关注执行update命令,有写入错误时返回第一个错误,有写关注错误时返回*mgo.LastError
+// mgo的Update和Bulk不支持arrayFilters,也无法区分upsert插入的条数
+func runUpdate(session *mgo.Session, database, collection string, updates []M, ordered bool) (result updateResult, err error) {
+	cmd := bson.D{
+		{Name: "update", Value: collection},
+		{Name: "updates", Value: updates},
+		{Name: "ordered", Value: ordered},
+	}
+	if wc := writeConcern(session); wc != nil {
+		cmd = append(cmd, bson.DocElem{Name: "writeConcern", Value: wc})
+	}
+	if err := session.DB(database).Run(cmd, &result); err != nil {
+		return result, err
+	}
+	if len(result.WriteErrors) > 0 {
+		return result, &mgo.QueryError{Code: result.WriteErrors[0].Code, Message: result.WriteErrors[0].ErrMsg}
+	}
+	if wce := result.WriteConcernError; wce != nil {
+		return result, &mgo.LastError{Code: wce.Code, Err: wce.ErrMsg, WTimeout: wce.Code == 64}
+	}
+	return result, nil
+}
+
+// writeConcern 将session通过SetSafe设置的写关注转换为命令的writeConcern,使用服务端默认值时返回nil
+func writeConcern(session *mgo.Session) M {
+	safe := session.Safe()
+	if safe == nil {
+		return M{"w": 0}
+	}
+	wc := M{}
+	if safe.WMode != "" {
+		wc["w"] = safe.WMode
+	} else if safe.W > 0 {
+		wc["w"] = safe.W
+	}
+	if safe.J {
+		wc["j"] = true
+	}
+	if safe.FSync {
+		wc["fsync"] = true
+	}
+	if safe.WTimeout > 0 {
+		wc["wtimeout"] = safe.WTimeout
+	}
+	if len(wc) == 0 {
+		return nil
+	}
+	return wc
+}
+
+// pairsToInterfaces 将[]M转换为[]interface{}
+func pairsToInterfaces(docs []M) []interface{} {
+	list := make([]interface{}, len(docs))
+	for i, doc := range docs {
+		list[i] = doc
+	}
+	return list
+}
@@ -0,0 +1,61 @@
+package mongo
+
+import "testing"
+
+func TestBulkUpsertCounts(t *testing.T) {
+	c := testClient(t)
+	coll := testCollection(t, c, "bulk_upsert")
+	if err := c.Insert(testDB, coll, M{"sku": "a", "qty": 1}, M{"sku": "b", "qty": 2}); err != nil {
+		t.Fatal(err)
+	}
+	result, err := c.BulkUpsert(testDB, coll, "sku", []M{
+		{"sku": "a", "qty": 1}, //存在且未变化
+		{"sku": "b", "qty": 3}, //存在且修改
+		{"sku": "c", "qty": 4}, //新插入
+		{"sku": "d", "qty": 5}, //新插入
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := BulkResult{Matched: 2, Modified: 1, Upserted: 2}
+	if result != want {
+		t.Fatalf("BulkUpsert = %+v, want %+v", result, want)
+	}
+	count, err := c.GetCount(testDB, coll, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 4 {
+		t.Fatalf("count = %d, want 4", count)
+	}
+}
+
+func BenchmarkInsert10k(b *testing.B) {
+	c := testClient(b)
+	docs := make([]interface{}, 10000)
+	for i := range docs {
+		docs[i] = M{"n": i}
+	}
+	b.Run("Single", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			coll := testCollection(b, c, "bench_insert")
+			b.StartTimer()
+			for _, doc := range docs {
+				if err := c.Insert(testDB, coll, doc); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("Bulk", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			coll := testCollection(b, c, "bench_insert")
+			b.StartTimer()
+			if _, err := c.Bulk(testDB, coll).Insert(docs...).Run(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package mongo
+
+// Collection 绑定数据库和集合的客户端,方法省略database和collection参数
+type Collection struct {
+	client     *Client
+	database   string
+	collection string
+}
+
+// C 返回绑定database和collection的客户端,如orders := client.C("app", "orders")
+func (c *Client) C(database, collection string) *Collection {
+	return &Collection{client: c, database: database, collection: collection}
+}
+
+// C 返回绑定当前数据库和collection的客户端
+func (d *DBClient) C(collection string) *Collection {
+	return d.client.C(d.database, collection)
+}
+
+// Name 返回绑定的集合名称
+func (c *Collection) Name() string {
+	return c.collection
+}
+
+// Find 按查询选项返回多行结果集
+func (c *Collection) Find(query, fields M, opts FindOptions, result interface{}) error {
+	return c.client.GetResultOpt(c.database, c.collection, query, fields, opts, result)
+}
+
+// Insert 插入数据
+func (c *Collection) Insert(docs ...interface{}) error {
+	return c.client.Insert(c.database, c.collection, docs...)
+}
+
+// Update 更新数据,不存在报ErrNotFound
+func (c *Collection) Update(selector, update M) error {
+	return c.client.Update(c.database, c.collection, selector, update)
+}
+
+// Remove 删除数据
+func (c *Collection) Remove(selector M) error {
+	return c.client.Remove(c.database, c.collection, selector)
+}
+
+// Count 返回统计条数
+func (c *Collection) Count(query M) (int, error) {
+	return c.client.GetCount(c.database, c.collection, query)
+}
+
+// Pipe 使用管道进行聚合计算并返回多行结果集
+func (c *Collection) Pipe(pipeline []M, result *[]M) error {
+	return c.client.GetPipeResult(c.database, c.collection, pipeline, result)
+}
@@ -0,0 +1,300 @@
+package mongo
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/globalsign/mgo"
+)
+
+// contextMaxTime 将ctx的剩余时间设为服务端的maxTimeMS
+func contextMaxTime(ctx context.Context, find *mgo.Query) {
+	if deadline, ok := ctx.Deadline(); ok {
+		find.SetMaxTime(time.Until(deadline))
+	}
+}
+
+// contextMaxTimeMS 返回ctx截止时间对应的maxTimeMS,用于直接执行的find命令,没有截止时间时返回0
+func contextMaxTimeMS(ctx context.Context) int {
+	if deadline, ok := ctx.Deadline(); ok {
+		if ms := int(time.Until(deadline) / time.Millisecond); ms > 0 {
+			return ms
+		}
+		return 1
+	}
+	return 0
+}
+
+// runContext 在goroutine中执行fn,fn解码到与result同类型的临时值,成功后再复制到result
+// ctx取消时立即关闭session并返回ctx.Err(),result不会被写入,fn之后的getMore等操作因session已关闭而终止,其他情况由调用方关闭session
+// mgo无法中断已经发出的请求,该请求仍占用连接直到服务端返回或socket超时
+func runContext(ctx context.Context, session *mgo.Session, result interface{}, fn func(result interface{}) error) error {
+	tmp := result
+	if rv := reflect.ValueOf(result); rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		tmp = reflect.New(rv.Type().Elem()).Interface()
+	}
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				//取消后session已关闭,mgo在后续操作中会panic
+				if ctx.Err() == nil {
+					panic(r)
+				}
+				done <- ctx.Err()
+			}
+		}()
+		done <- fn(tmp)
+	}()
+	select {
+	case err := <-done:
+		if err == nil && tmp != result {
+			reflect.ValueOf(result).Elem().Set(reflect.ValueOf(tmp).Elem())
+		}
+		return err
+	case <-ctx.Done():
+		session.Close()
+		return ctx.Err()
+	}
+}
+
+// PingContext 监测数据库连接,ctx取消或超时时立即返回ctx.Err()
+func (c *Client) PingContext(ctx context.Context) (err error) {
+	defer c.observe(&err, "PingContext", "", "", time.Now())
+	ctx, end := c.startSpan(ctx, "PingContext", "", "")
+	defer end(&err)
+	if err := c.ready(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	err = runContext(ctx, session, nil, func(interface{}) error {
+		return session.Ping()
+	})
+	if err != nil && err != ctx.Err() {
+		c.connErr = c.hostError(err)
+		return c.connErr
+	}
+	return err
+}
+
+// WaitReady 反复连接并Ping直到成功或ctx取消,成功后清除保存的连接错误,用于启动时等待数据库就绪
+// interval为两次尝试的间隔,单次连接仍受连接超时限制,客户端已关闭时返回ErrClosed,之前Set*方法的设置会应用到新连接
+// WaitReady会替换内部session,不能与其他操作并发调用,应在WaitReady返回后再开始使用客户端
+func (c *Client) WaitReady(ctx context.Context, interval time.Duration) (err error) {
+	defer c.observe(&err, "WaitReady", "", "", time.Now())
+	for {
+		if c.connErr == ErrClosed {
+			return ErrClosed
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if c.session == nil {
+			if c.dial == nil {
+				return ErrNotConnected
+			}
+			if c.connect() == nil {
+				return nil
+			}
+		} else {
+			session := c.session.Copy()
+			err := session.Ping()
+			session.Close()
+			if err == nil {
+				c.connErr = nil
+				return nil
+			}
+			c.connErr = c.hostError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// GetRowContext 返回一行数据,支持ctx取消,取消时返回ctx.Err()且result不会被写入
+func (c *Client) GetRowContext(ctx context.Context, database, collection string, query, options M, result interface{}) (err error) {
+	defer c.observe(&err, "GetRowContext", database, collection, time.Now())
+	ctx, end := c.startSpan(ctx, "GetRowContext", database, collection)
+	defer end(&err)
+	if err := c.ready(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	//按名称指定索引
+	if opts := findOptions(options); useFindCommand(opts) {
+		opts = FindOptions{Sort: opts.Sort, Hint: opts.Hint, Limit: 1, MaxTimeMS: contextMaxTimeMS(ctx)}
+		return runContext(ctx, session, result, func(result interface{}) error {
+			return iterOne(findIter(session, conn, query, nil, opts), result)
+		})
+	}
+	find := conn.Find(query)
+	//排序
+	if options["Sort"] != "" {
+		if sort, ok := options["Sort"].(Sort); ok {
+			find.Sort(sort...)
+		}
+	}
+	//索引
+	if hint, ok := options["Hint"].(string); ok && hint != "" {
+		find.Hint(queryHint(hint)...)
+	}
+	contextMaxTime(ctx, find)
+	return runContext(ctx, session, result, func(result interface{}) error {
+		return find.One(result)
+	})
+}
+
+// GetResultContext 返回多行结果集,支持ctx取消,取消时返回ctx.Err()且result不会被写入
+func (c *Client) GetResultContext(ctx context.Context, database, collection string, query, fields, options M, result interface{}) (err error) {
+	defer c.observe(&err, "GetResultContext", database, collection, time.Now())
+	ctx, end := c.startSpan(ctx, "GetResultContext", database, collection)
+	defer end(&err)
+	if err := c.ready(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	if opts := findOptions(options); useFindCommand(opts) {
+		opts.MaxTimeMS = contextMaxTimeMS(ctx)
+		return runContext(ctx, session, result, func(result interface{}) error {
+			return findIter(session, conn, query, fields, opts).All(result)
+		})
+	}
+	find := conn.Find(query).Select(fields)
+	applyFindOptions(find, findOptions(options))
+	contextMaxTime(ctx, find)
+	return runContext(ctx, session, result, func(result interface{}) error {
+		return find.All(result)
+	})
+}
+
+// GetCountContext 返回统计条数,支持ctx取消,取消时返回0和ctx.Err()
+func (c *Client) GetCountContext(ctx context.Context, database, collection string, query M) (count int, err error) {
+	defer c.observe(&err, "GetCountContext", database, collection, time.Now())
+	ctx, end := c.startSpan(ctx, "GetCountContext", database, collection)
+	defer end(&err)
+	if err := c.ready(); err != nil {
+		return 0, err
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	find := conn.Find(query)
+	contextMaxTime(ctx, find)
+	err = runContext(ctx, session, &count, func(result interface{}) error {
+		n, err := find.Count()
+		*result.(*int) = n
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// InsertContext 插入数据,支持ctx取消,取消时返回ctx.Err(),已发出的插入仍可能在服务端完成
+func (c *Client) InsertContext(ctx context.Context, database, collection string, docs ...interface{}) (err error) {
+	defer c.observe(&err, "InsertContext", database, collection, time.Now())
+	ctx, end := c.startSpan(ctx, "InsertContext", database, collection)
+	defer end(&err)
+	if err := c.ready(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	return runContext(ctx, session, nil, func(interface{}) error {
+		return conn.Insert(docs...)
+	})
+}
+
+// UpdateContext 更新数据,不存在报ErrNotFound,支持ctx取消,取消时返回ctx.Err(),已发出的更新仍可能在服务端完成
+func (c *Client) UpdateContext(ctx context.Context, database, collection string, selector, update M) (err error) {
+	defer c.observe(&err, "UpdateContext", database, collection, time.Now())
+	ctx, end := c.startSpan(ctx, "UpdateContext", database, collection)
+	defer end(&err)
+	if err := c.ready(); err != nil {
+		return err
+	}
+	if err := c.checkSelector(selector); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	return runContext(ctx, session, nil, func(interface{}) error {
+		return conn.Update(selector, update)
+	})
+}
+
+// GetPipeRowContext 使用管道进行聚合计算并返回一行数据,支持ctx取消,取消时返回ctx.Err()且result不会被写入
+func (c *Client) GetPipeRowContext(ctx context.Context, database, collection string, pipeline []M, result *M) (err error) {
+	defer c.observe(&err, "GetPipeRowContext", database, collection, time.Now())
+	ctx, end := c.startSpan(ctx, "GetPipeRowContext", database, collection)
+	defer end(&err)
+	if err := c.ready(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	pipe := conn.Pipe(pipeline)
+	if deadline, ok := ctx.Deadline(); ok {
+		pipe.SetMaxTime(time.Until(deadline))
+	}
+	return runContext(ctx, session, result, func(result interface{}) error {
+		return pipe.One(result)
+	})
+}
+
+// GetPipeResultContext 使用管道进行聚合计算并返回多行结果集,支持ctx取消,取消时返回ctx.Err()且result不会被写入
+func (c *Client) GetPipeResultContext(ctx context.Context, database, collection string, pipeline []M, result *[]M) (err error) {
+	defer c.observe(&err, "GetPipeResultContext", database, collection, time.Now())
+	ctx, end := c.startSpan(ctx, "GetPipeResultContext", database, collection)
+	defer end(&err)
+	if err := c.ready(); err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	pipe := conn.Pipe(pipeline)
+	if deadline, ok := ctx.Deadline(); ok {
+		pipe.SetMaxTime(time.Until(deadline))
+	}
+	return runContext(ctx, session, result, func(result interface{}) error {
+		return pipe.All(result)
+	})
+}
@@ -0,0 +1,92 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/globalsign/mgo"
+)
+
+func TestGetResultContextCancel(t *testing.T) {
+	c := testClient(t)
+	coll := testCollection(t, c, "context_cancel")
+	if err := c.Insert(testDB, coll, M{"n": 1}, M{"n": 2}); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	var result []M
+	err := c.GetResultContext(ctx, testDB, coll, M{"$where": "sleep(500) || true"}, nil, nil, &result)
+	if err == nil {
+		t.Skip("server finished before cancel")
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	time.Sleep(time.Second)
+	if result != nil {
+		t.Fatalf("result written after cancel: %v", result)
+	}
+}
+
+func TestWaitReadyKeepsSettings(t *testing.T) {
+	attempts := 0
+	c := &Client{host: "db", dial: func() (*mgo.Session, error) {
+		attempts++
+		return nil, errors.New("connection refused")
+	}}
+	c.SetMajority()
+	c.SetMode(SecondaryPreferred, true)
+	c.SetPoolLimit(8)
+	c.SetSocketTimeout(time.Minute)
+	if c.safe == nil || c.safe.WMode != "majority" || c.mode == nil || *c.mode != SecondaryPreferred || c.poolLimit != 8 || c.sockTimeout != time.Minute {
+		t.Fatal("settings not stored before connect")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := c.WaitReady(ctx, 10*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WaitReady = %v, want context.DeadlineExceeded", err)
+	}
+	if attempts < 2 {
+		t.Fatalf("attempts = %d, want retries", attempts)
+	}
+}
+
+func TestWaitReadyAppliesSettings(t *testing.T) {
+	shared := testClient(t)
+	c := &Client{host: shared.host, sockTimeout: DefaultSocketTimeout, dial: func() (*mgo.Session, error) {
+		return shared.session.Copy(), nil
+	}}
+	c.SetMajority()
+	c.SetMode(PrimaryPreferred, true)
+	if err := c.WaitReady(context.Background(), 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if safe := c.session.Safe(); safe == nil || safe.WMode != "majority" {
+		t.Fatalf("Safe = %+v, want majority", safe)
+	}
+	if mode := c.session.Mode(); mode != PrimaryPreferred {
+		t.Fatalf("Mode = %v, want PrimaryPreferred", mode)
+	}
+}
+
+func TestPingContextDeadline(t *testing.T) {
+	proxy, c := testProxyClient(t)
+	proxy.setDelay(500 * time.Millisecond)
+	defer proxy.setDelay(0)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := c.PingContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("PingContext = %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("PingContext returned after %v", elapsed)
+	}
+	if err := c.ready(); err != nil {
+		t.Fatalf("deadline stored as connection error: %v", err)
+	}
+}
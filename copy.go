@@ -0,0 +1,85 @@
+package mongo
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+// CopyOptions 复制集合数据选项
+type CopyOptions struct {
+	BatchSize int  //每批插入的条数,为0时每批1000条
+	DropID    bool //不保留原_id,插入时生成新的ObjectID,用于向已有数据的集合追加
+}
+
+// Copy 将源集合中满足query的数据按batchSize分批复制到目标集合,保留原_id,返回已复制的条数
+// 支持跨数据库复制,批次之间不是原子操作,出错时之前批次的数据已经插入
+func (c *Client) Copy(srcDB, srcColl, dstDB, dstColl string, query M, batchSize int) (int, error) {
+	return c.CopyOpt(srcDB, srcColl, dstDB, dstColl, query, CopyOptions{BatchSize: batchSize})
+}
+
+// CopyOpt 按选项将源集合中满足query的数据复制到目标集合,返回已复制的条数
+func (c *Client) CopyOpt(srcDB, srcColl, dstDB, dstColl string, query M, opts CopyOptions) (copied int, err error) {
+	defer c.observe(&err, "Copy", srcDB, srcColl, time.Now())
+	if err := c.ready(); err != nil {
+		return 0, err
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	//写入使用单独的session,写入超时关闭session时不影响读取游标的关闭
+	dstSession := c.session.Copy()
+	defer dstSession.Close()
+	src := session.DB(srcDB).C(srcColl)
+	dst := dstSession.DB(dstDB).C(dstColl)
+	flush := func(batch []interface{}) error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := c.withWrite(dstSession, func() error {
+			return dst.Insert(batch...)
+		}); err != nil {
+			return err
+		}
+		copied += len(batch)
+		return nil
+	}
+	iter := src.Find(query).Batch(batchSize).Iter()
+	batch := make([]interface{}, 0, batchSize)
+	//保留_id时按原始字节复制,删除_id时使用bson.D,都保持原文档的字段顺序
+	next := func() (interface{}, bool) {
+		if !opts.DropID {
+			var raw bson.Raw
+			ok := iter.Next(&raw)
+			return raw, ok
+		}
+		var doc bson.D
+		if !iter.Next(&doc) {
+			return nil, false
+		}
+		for i, elem := range doc {
+			if elem.Name == "_id" {
+				doc = append(doc[:i], doc[i+1:]...)
+				break
+			}
+		}
+		return doc, true
+	}
+	for doc, ok := next(); ok; doc, ok = next() {
+		batch = append(batch, doc)
+		if len(batch) == batchSize {
+			if err := flush(batch); err != nil {
+				iter.Close()
+				return copied, err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := iter.Close(); err != nil {
+		return copied, err
+	}
+	return copied, flush(batch)
+}
@@ -0,0 +1,46 @@
+package mongo
+
+import (
+	"testing"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+func TestCopyOpt(t *testing.T) {
+	c := testClient(t)
+	src := testCollection(t, c, "copy_src")
+	docs := make([]interface{}, 10)
+	for i := range docs {
+		docs[i] = bson.D{{Name: "z", Value: i}, {Name: "a", Value: i}, {Name: "n", Value: i}}
+	}
+	if err := c.Insert(testDB, src, docs...); err != nil {
+		t.Fatal(err)
+	}
+	filter := M{"n": M{"$gte": 6}}
+	for _, dropID := range []bool{false, true} {
+		dst := testCollection(t, c, "copy_dst")
+		copied, err := c.CopyOpt(testDB, src, testDB, dst, filter, CopyOptions{BatchSize: 3, DropID: dropID})
+		if err != nil || copied != 4 {
+			t.Fatalf("DropID=%v: CopyOpt = %d, %v, want 4", dropID, copied, err)
+		}
+		if n, err := c.GetCount(testDB, dst, M{}); err != nil || n != 4 {
+			t.Fatalf("DropID=%v: destination count = %d, %v, want 4", dropID, n, err)
+		}
+		var got []bson.D
+		if err := c.GetResult(testDB, dst, M{}, nil, M{"Sort": Sort{"n"}}, &got); err != nil {
+			t.Fatal(err)
+		}
+		for _, doc := range got {
+			if len(doc) != 4 || doc[0].Name != "_id" || doc[1].Name != "z" || doc[2].Name != "a" || doc[3].Name != "n" {
+				t.Fatalf("DropID=%v: field order changed: %v", dropID, doc)
+			}
+		}
+		var orig bson.M
+		if err := c.GetRow(testDB, src, M{"n": 6}, nil, &orig); err != nil {
+			t.Fatal(err)
+		}
+		if same := got[0][0].Value == orig["_id"]; same == dropID {
+			t.Fatalf("DropID=%v: _id %v, source _id %v", dropID, got[0][0].Value, orig["_id"])
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package mongo
+
+// DBClient 绑定数据库的客户端,方法与Client相同但省略database参数
+type DBClient struct {
+	client   *Client
+	database string
+}
+
+// DB 返回绑定database的客户端,适用于只使用一个数据库的场景
+func (c *Client) DB(database string) *DBClient {
+	return &DBClient{client: c, database: database}
+}
+
+// Name 返回绑定的数据库名称
+func (d *DBClient) Name() string {
+	return d.database
+}
+
+// GetRow 返回一行数据
+func (d *DBClient) GetRow(collection string, query, options M, result interface{}) error {
+	return d.client.GetRow(d.database, collection, query, options, result)
+}
+
+// GetById 根据_id返回一行数据
+func (d *DBClient) GetById(collection string, id ObjectID, result interface{}) error {
+	return d.client.GetById(d.database, collection, id, result)
+}
+
+// GetResult 返回多行结果集
+func (d *DBClient) GetResult(collection string, query, fields, options M, result interface{}) error {
+	return d.client.GetResult(d.database, collection, query, fields, options, result)
+}
+
+// GetResultOpt 按查询选项返回多行结果集
+func (d *DBClient) GetResultOpt(collection string, query, fields M, opts FindOptions, result interface{}) error {
+	return d.client.GetResultOpt(d.database, collection, query, fields, opts, result)
+}
+
+// GetCount 返回统计条数
+func (d *DBClient) GetCount(collection string, query M) (int, error) {
+	return d.client.GetCount(d.database, collection, query)
+}
+
+// Exists 返回是否存在匹配的数据
+func (d *DBClient) Exists(collection string, query M) (bool, error) {
+	return d.client.Exists(d.database, collection, query)
+}
+
+// Insert 插入数据
+func (d *DBClient) Insert(collection string, docs ...interface{}) error {
+	return d.client.Insert(d.database, collection, docs...)
+}
+
+// Update 更新数据,不存在报ErrNotFound
+func (d *DBClient) Update(collection string, selector, update M) error {
+	return d.client.Update(d.database, collection, selector, update)
+}
+
+// UpdateId 根据_id更新数据,不存在报ErrNotFound
+func (d *DBClient) UpdateId(collection string, id ObjectID, update M) error {
+	return d.client.UpdateId(d.database, collection, id, update)
+}
+
+// UpdateAll 批量更新数据,不存在报ErrNotFound
+func (d *DBClient) UpdateAll(collection string, selector, update M) (map[string]interface{}, error) {
+	return d.client.UpdateAll(d.database, collection, selector, update)
+}
+
+// Upsert 更新数据,不存在会新插入数据
+func (d *DBClient) Upsert(collection string, selector, update M) (map[string]interface{}, error) {
+	return d.client.Upsert(d.database, collection, selector, update)
+}
+
+// Remove 删除数据
+func (d *DBClient) Remove(collection string, selector M) error {
+	return d.client.Remove(d.database, collection, selector)
+}
+
+// RemoveId 根据_id删除数据,不存在报ErrNotFound
+func (d *DBClient) RemoveId(collection string, id ObjectID) error {
+	return d.client.RemoveId(d.database, collection, id)
+}
+
+// RemoveAll 批量删除数据
+func (d *DBClient) RemoveAll(collection string, selector M) (int, error) {
+	return d.client.RemoveAll(d.database, collection, selector)
+}
+
+// GetPipeRow 使用管道进行聚合计算并返回一行数据
+func (d *DBClient) GetPipeRow(collection string, pipeline []M, result *M) error {
+	return d.client.GetPipeRow(d.database, collection, pipeline, result)
+}
+
+// GetPipeResult 使用管道进行聚合计算并返回多行结果集
+func (d *DBClient) GetPipeResult(collection string, pipeline []M, result *[]M) error {
+	return d.client.GetPipeResult(d.database, collection, pipeline, result)
+}
+
+// RunCommand 在数据库上执行任意命令
+func (d *DBClient) RunCommand(cmd M, result interface{}) error {
+	return d.client.RunCommand(d.database, cmd, result)
+}
+
+// CollectionNames 返回数据库中的所有集合名称
+func (d *DBClient) CollectionNames() ([]string, error) {
+	return d.client.CollectionNames(d.database)
+}
+
+// DropCollection 删除集合,集合不存在时返回nil
+func (d *DBClient) DropCollection(collection string) error {
+	return d.client.DropCollection(d.database, collection)
+}
@@ -0,0 +1,63 @@
+package mongo
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Error 操作错误,记录出错的操作名称、数据库和集合
+// 可通过errors.As获取,通过errors.Is判断原始错误,如errors.Is(err, ErrNotFound),包装后err == ErrNotFound不再成立
+type Error struct {
+	Op         string
+	Database   string
+	Collection string
+	Err        error
+}
+
+// Error 返回错误信息
+func (e Error) Error() string {
+	if e.Collection != "" {
+		return e.Op + " " + e.Database + "." + e.Collection + ": " + e.Err.Error()
+	}
+	if e.Database != "" {
+		return e.Op + " " + e.Database + ": " + e.Err.Error()
+	}
+	return e.Op + ": " + e.Err.Error()
+}
+
+// Unwrap 返回原始错误
+func (e Error) Unwrap() error {
+	return e.Err
+}
+
+// wrapError 将非空错误包装为Error,已经是Error的不重复包装
+func wrapError(err *error, op, database, collection string) {
+	if *err == nil {
+		return
+	}
+	if _, ok := (*err).(Error); ok {
+		return
+	}
+	*err = Error{Op: op, Database: database, Collection: collection, Err: *err}
+}
+
+// errorCause 返回Error包装的原始错误,其他错误原样返回
+func errorCause(err error) error {
+	if e, ok := err.(Error); ok {
+		return e.Err
+	}
+	return err
+}
+
+// credentialPattern 匹配连接地址中的user:pass@
+var credentialPattern = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://)?[^\s/@:]+:[^\s/]*@`)
+
+// redact 去掉字符串中的user:pass@,避免密码出现在日志中
+func redact(s string) string {
+	return credentialPattern.ReplaceAllString(s, "${1}")
+}
+
+// hostError 返回带host的连接错误,错误信息中的用户名和密码会被去掉
+func (c *Client) hostError(err error) error {
+	return fmt.Errorf("host: %s error: %s", redact(c.host), redact(err.Error()))
+}
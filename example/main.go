@@ -12,9 +12,13 @@ func main() {
 	fmt.Println(client)
 
 	//监测连接错误
-	err := mongo.Ping()
+	err := client.Ping()
 	fmt.Println(err, mongo.ErrNotFound)
 
+	//多个连接互不影响
+	otherClient := mongo.Conn("127.0.0.1:27018")
+	fmt.Println(otherClient.Ping())
+
 	//获取objectID
 	objectID := mongo.NewObjectID()
 	fmt.Println(objectID)
@@ -30,4 +34,15 @@ func main() {
 	//16进制转成objectID
 	newObjectID := mongo.ObjectIDHex(hexID)
 	fmt.Println(newObjectID)
+
+	//游标迭代,内存占用恒定
+	iter, err := client.Iter("test", "users", mongo.M{}, mongo.FindOptions{BatchSize: 1000})
+	if err == nil {
+		var row mongo.M
+		var count int
+		for iter.Next(&row) {
+			count++
+		}
+		fmt.Println(count, iter.Err(), iter.Close())
+	}
 }
@@ -0,0 +1,112 @@
+package mongo
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+// ExportJSON 按查询选项将结果逐行以MongoDB扩展JSON写入w,返回写入的条数,使用游标读取,内存占用与结果集大小无关
+// ObjectID写为{"$oid": "..."},时间写为{"$date": "..."},字段按名称排序
+func (c *Client) ExportJSON(database, collection string, query M, opts FindOptions, w io.Writer) (count int, err error) {
+	defer c.observe(&err, "ExportJSON", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return 0, err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	find := conn.Find(query)
+	applyFindOptions(find, opts)
+	iter := find.Iter()
+	var doc M
+	for iter.Next(&doc) {
+		line, err := bson.MarshalJSON(doc)
+		if err != nil {
+			iter.Close()
+			return count, err
+		}
+		//MarshalJSON输出已以换行结尾
+		if _, err := w.Write(line); err != nil {
+			iter.Close()
+			return count, err
+		}
+		count++
+		doc = nil
+	}
+	return count, maxTimeError(iter.Close())
+}
+
+// ExportCSV 按查询选项将结果写为CSV,第一行为fields表头,每条数据一行,返回写入的数据条数
+// fields同时作为查询的字段,支持嵌套字段如"address.city",缺少的字段为空,ObjectID写为十六进制,时间写为RFC3339
+func (c *Client) ExportCSV(database, collection string, query M, fields []string, opts FindOptions, w io.Writer) (count int, err error) {
+	defer c.observe(&err, "ExportCSV", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return 0, err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	selector := M{"_id": 0}
+	for _, field := range fields {
+		selector[field] = 1
+	}
+	find := conn.Find(query).Select(selector)
+	applyFindOptions(find, opts)
+	writer := csv.NewWriter(w)
+	if err := writer.Write(fields); err != nil {
+		return 0, err
+	}
+	iter := find.Iter()
+	var doc M
+	row := make([]string, len(fields))
+	for iter.Next(&doc) {
+		for i, field := range fields {
+			row[i] = csvValue(lookupField(doc, field))
+		}
+		if err := writer.Write(row); err != nil {
+			iter.Close()
+			return count, err
+		}
+		count++
+		doc = nil
+	}
+	if err := iter.Close(); err != nil {
+		return count, maxTimeError(err)
+	}
+	writer.Flush()
+	return count, writer.Error()
+}
+
+// lookupField 按"a.b.c"形式的路径返回嵌套字段的值,不存在时返回nil
+func lookupField(doc M, path string) interface{} {
+	var value interface{} = doc
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(M)
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
+
+// csvValue 将字段值转换为CSV单元格
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case ObjectID:
+		return v.Hex()
+	case time.Time:
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,83 @@
+package mongo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestExportJSON(t *testing.T) {
+	c := testClient(t)
+	coll := testSeed(t, c, "export_json", 1000)
+	var buf bytes.Buffer
+	count, err := c.ExportJSON(testDB, coll, nil, FindOptions{BatchSize: 100}, &buf)
+	if err != nil || count != 1000 {
+		t.Fatalf("ExportJSON = %d, %v, want 1000", count, err)
+	}
+	lines := 0
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var doc map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &doc); err != nil {
+			t.Fatalf("line %d: %v: %s", lines+1, err, scanner.Bytes())
+		}
+		if _, ok := doc["_id"].(map[string]interface{})["$oid"]; !ok {
+			t.Fatalf("line %d: _id not written as $oid: %s", lines+1, scanner.Bytes())
+		}
+		lines++
+	}
+	if lines != 1000 {
+		t.Fatalf("got %d lines, want 1000", lines)
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	c := testClient(t)
+	coll := testCollection(t, c, "export_csv")
+	err := c.Insert(testDB, coll,
+		M{"n": 1, "name": "alice", "address": M{"city": "Beijing", "zip": "100000"}},
+		M{"n": 2, "name": "bob, jr", "address": M{"zip": "200000"}},
+		M{"n": 3, "address": "unknown"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	count, err := c.ExportCSV(testDB, coll, nil, []string{"n", "name", "address.city"}, FindOptions{Sort: Sort{"n"}}, &buf)
+	if err != nil || count != 3 {
+		t.Fatalf("ExportCSV = %d, %v, want 3", count, err)
+	}
+	want := "n,name,address.city\n" +
+		"1,alice,Beijing\n" +
+		"2,\"bob, jr\",\n" +
+		"3,,\n"
+	if buf.String() != want {
+		t.Fatalf("csv =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestCSVValue(t *testing.T) {
+	id := NewObjectID()
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	doc := M{"a": M{"b": M{"c": 1}}, "s": "x"}
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{nil, ""},
+		{"text", "text"},
+		{id, id.Hex()},
+		{at, "2024-01-02T03:04:05Z"},
+		{1.5, "1.5"},
+		{lookupField(doc, "a.b.c"), "1"},
+		{lookupField(doc, "a.x.c"), ""},
+		{lookupField(doc, "s.c"), ""},
+	}
+	for _, tt := range tests {
+		if got := csvValue(tt.value); got != tt.want {
+			t.Errorf("csvValue(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package mongo
+
+// GetRowT 返回一行数据并解码为T类型
+func GetRowT[T any](c *Client, db, coll string, query M) (T, error) {
+	var result T
+	err := c.GetRow(db, coll, query, nil, &result)
+	return result, err
+}
+
+// GetResultT 按查询选项返回多行结果集并解码为[]T
+func GetResultT[T any](c *Client, db, coll string, query M, opts FindOptions) ([]T, error) {
+	var result []T
+	err := c.GetResultOpt(db, coll, query, nil, opts, &result)
+	return result, err
+}
+
+// GetResultMapT 按查询选项返回多行结果集,并以keyFn返回的值为键构建map,键重复时后面的数据覆盖前面的
+func GetResultMapT[T any](c *Client, db, coll string, query M, keyFn func(T) string, opts FindOptions) (map[string]T, error) {
+	rows, err := GetResultT[T](c, db, coll, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]T, len(rows))
+	for _, row := range rows {
+		result[keyFn(row)] = row
+	}
+	return result, nil
+}
@@ -0,0 +1,71 @@
+package mongo
+
+import (
+	"io"
+	"time"
+)
+
+// GridFSPut 将r中的数据流式写入GridFS文件并返回文件_id,prefix为空时使用"fs"
+func (c *Client) GridFSPut(database, prefix, filename string, r io.Reader) (id ObjectID, err error) {
+	defer c.observe(&err, "GridFSPut", database, "", time.Now())
+	if err := c.ready(); err != nil {
+		return "", err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	gfs := session.DB(database).GridFS(gridFSPrefix(prefix))
+	file, err := gfs.Create(filename)
+	if err != nil {
+		return "", err
+	}
+	if _, err = io.Copy(file, r); err != nil {
+		file.Abort()
+		file.Close()
+		return "", err
+	}
+	if err = file.Close(); err != nil {
+		return "", err
+	}
+	id, _ = file.Id().(ObjectID)
+	return id, nil
+}
+
+// GridFSGet 将GridFS文件的数据流式写入w,同名文件存在多个版本时读取最新的版本
+func (c *Client) GridFSGet(database, prefix, filename string, w io.Writer) (err error) {
+	defer c.observe(&err, "GridFSGet", database, "", time.Now())
+	if err := c.ready(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	gfs := session.DB(database).GridFS(gridFSPrefix(prefix))
+	file, err := gfs.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(w, file)
+	return err
+}
+
+// GridFSRemove 删除GridFS中所有同名文件
+func (c *Client) GridFSRemove(database, prefix, filename string) (err error) {
+	defer c.observe(&err, "GridFSRemove", database, "", time.Now())
+	if err := c.ready(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	gfs := session.DB(database).GridFS(gridFSPrefix(prefix))
+	return c.withWrite(session, func() error {
+		return gfs.Remove(filename)
+	})
+}
+
+// gridFSPrefix 返回GridFS集合前缀,为空时使用默认的"fs"
+func gridFSPrefix(prefix string) string {
+	if prefix == "" {
+		return "fs"
+	}
+	return prefix
+}
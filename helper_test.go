@@ -0,0 +1,153 @@
+package mongo
+
+import (
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testDB 测试使用的数据库
+const testDB = "mongo_test"
+
+var (
+	testOnce   sync.Once
+	testShared *Client
+	testErr    error
+)
+
+// testAddrs 返回MONGO_ADDR中的测试地址,默认127.0.0.1:27017
+func testAddrs() []string {
+	addr := os.Getenv("MONGO_ADDR")
+	if addr == "" {
+		addr = "127.0.0.1:27017"
+	}
+	return strings.Split(addr, ",")
+}
+
+// testClient 返回连接testAddrs的客户端,连接不上时跳过测试
+func testClient(tb testing.TB) *Client {
+	tb.Helper()
+	testOnce.Do(func() {
+		testShared, testErr = ConnWithOptions(ConnOptions{Addrs: testAddrs(), Timeout: time.Second})
+	})
+	if testErr != nil {
+		tb.Skipf("mongodb not reachable: %v", testErr)
+	}
+	return testShared
+}
+
+// testCollection 返回清空后的测试集合名称
+func testCollection(tb testing.TB, c *Client, name string) string {
+	tb.Helper()
+	if err := c.DropCollection(testDB, name); err != nil {
+		tb.Fatal(err)
+	}
+	return name
+}
+
+// testSeed 清空集合后插入n条{n: i}数据,返回集合名称
+func testSeed(tb testing.TB, c *Client, name string, n int) string {
+	tb.Helper()
+	coll := testCollection(tb, c, name)
+	for i := 0; i < n; i += maxWriteBatch {
+		bulk := c.Bulk(testDB, coll).Unordered()
+		for j := i; j < n && j < i+maxWriteBatch; j++ {
+			bulk.Insert(M{"n": j})
+		}
+		if _, err := bulk.Run(); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return coll
+}
+
+// testProxy 转发到testAddrs的TCP代理,用于模拟网络中断和延迟
+type testProxy struct {
+	ln    net.Listener
+	mu    sync.Mutex
+	conns []net.Conn
+	delay time.Duration
+}
+
+// testProxyClient 返回通过testProxy连接的客户端,测试结束时关闭
+func testProxyClient(tb testing.TB) (*testProxy, *Client) {
+	tb.Helper()
+	testClient(tb)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	p := &testProxy{ln: ln}
+	go p.serve(testAddrs()[0])
+	tb.Cleanup(func() {
+		ln.Close()
+		p.drop()
+	})
+	c, err := ConnWithOptions(ConnOptions{Addrs: []string{ln.Addr().String()}, Timeout: time.Second})
+	if err != nil {
+		tb.Skipf("proxy not usable: %v", err)
+	}
+	tb.Cleanup(c.Close)
+	return p, c
+}
+
+func (p *testProxy) serve(target string) {
+	for {
+		src, err := p.ln.Accept()
+		if err != nil {
+			return
+		}
+		dst, err := net.Dial("tcp", target)
+		if err != nil {
+			src.Close()
+			continue
+		}
+		p.mu.Lock()
+		p.conns = append(p.conns, src, dst)
+		p.mu.Unlock()
+		go io.Copy(dst, src)
+		go p.reply(src, dst)
+	}
+}
+
+// reply 将服务端的响应转发给客户端,设置了delay时每次转发前等待
+func (p *testProxy) reply(src, dst net.Conn) {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := dst.Read(buf)
+		if n > 0 {
+			p.mu.Lock()
+			delay := p.delay
+			p.mu.Unlock()
+			time.Sleep(delay)
+			if _, err := src.Write(buf[:n]); err != nil {
+				return
+			}
+		}
+		if err != nil {
+			src.Close()
+			return
+		}
+	}
+}
+
+// setDelay 设置服务端响应的延迟
+func (p *testProxy) setDelay(d time.Duration) {
+	p.mu.Lock()
+	p.delay = d
+	p.mu.Unlock()
+}
+
+// drop 断开当前所有连接
+func (p *testProxy) drop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, conn := range p.conns {
+		conn.Close()
+	}
+	p.conns = nil
+}
@@ -0,0 +1,62 @@
+package mongo
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+// ImportJSON 从r读取每行一条的MongoDB扩展JSON数据,按batchSize分批插入,返回已插入的条数,batchSize为0时每批1000条
+// 支持{"$oid": "..."}转换为ObjectID,{"$date": "..."}转换为time.Time,空行会被忽略,遇到错误时停止
+func (c *Client) ImportJSON(database, collection string, r io.Reader, batchSize int) (inserted int, err error) {
+	defer c.observe(&err, "ImportJSON", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return 0, err
+	}
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	flush := func(batch []interface{}) error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := c.withWrite(session, func() error {
+			return conn.Insert(batch...)
+		}); err != nil {
+			return err
+		}
+		inserted += len(batch)
+		return nil
+	}
+	reader := bufio.NewReader(r)
+	batch := make([]interface{}, 0, batchSize)
+	for lineNo := 1; ; lineNo++ {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return inserted, readErr
+		}
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			doc := M{}
+			if err := bson.UnmarshalJSON(line, &doc); err != nil {
+				return inserted, fmt.Errorf("line %d: %s", lineNo, err.Error())
+			}
+			batch = append(batch, doc)
+			if len(batch) == batchSize {
+				if err := flush(batch); err != nil {
+					return inserted, err
+				}
+				batch = batch[:0]
+			}
+		}
+		if readErr == io.EOF {
+			return inserted, flush(batch)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package mongo
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImportJSON(t *testing.T) {
+	c := testClient(t)
+	coll := testCollection(t, c, "import_json")
+	id := NewObjectID()
+	input := `{"_id": {"$oid": "` + id.Hex() + `"}, "n": 1, "at": {"$date": "2024-01-02T03:04:05Z"}}
+{"n": 2}
+
+{"n": 3, "tags": ["a", "b"]}
+{"n": 4}
+{"n": 5}
+`
+	inserted, err := c.ImportJSON(testDB, coll, strings.NewReader(input), 2)
+	if err != nil || inserted != 5 {
+		t.Fatalf("ImportJSON = %d, %v, want 5", inserted, err)
+	}
+	if n, err := c.GetCount(testDB, coll, M{}); err != nil || n != 5 {
+		t.Fatalf("count = %d, %v, want 5", n, err)
+	}
+	var doc struct {
+		N  int       `bson:"n"`
+		At time.Time `bson:"at"`
+	}
+	if err := c.GetById(testDB, coll, id, &doc); err != nil {
+		t.Fatalf("$oid not imported as ObjectID: %v", err)
+	}
+	if want := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC); doc.N != 1 || !doc.At.Equal(want) {
+		t.Fatalf("doc = %+v, want n 1 at %v", doc, want)
+	}
+
+	inserted, err = c.ImportJSON(testDB, coll, strings.NewReader("{\"n\": 6}\n{bad\n{\"n\": 7}\n"), 10)
+	if err == nil || !strings.Contains(err.Error(), "line 2") || inserted != 0 {
+		t.Fatalf("invalid line: %d, %v, want a line 2 error and nothing inserted", inserted, err)
+	}
+}
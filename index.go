@@ -0,0 +1,87 @@
+package mongo
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo"
+)
+
+// Index 索引结构体
+type Index struct {
+	Key           []string      //索引字段,前缀"-"表示倒序,如[]string{"name", "-age"}
+	Unique        bool          //唯一索引
+	Background    bool          //后台创建
+	Sparse        bool          //稀疏索引
+	ExpireAfter   time.Duration //TTL索引过期时间,大于0时生效
+	Name          string        //索引名称
+	PartialFilter M             //部分索引条件,只索引匹配的数据,如M{"email": M{"$exists": true}}
+}
+
+// EnsureIndex 创建索引,索引已存在时不做任何操作
+func (c *Client) EnsureIndex(database, collection string, index Index) (err error) {
+	defer c.observe(&err, "EnsureIndex", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	return c.withRetry(session, func() error {
+		return conn.EnsureIndex(mgo.Index{
+			Key:           index.Key,
+			Unique:        index.Unique,
+			Background:    index.Background,
+			Sparse:        index.Sparse,
+			ExpireAfter:   index.ExpireAfter,
+			Name:          index.Name,
+			PartialFilter: index.PartialFilter,
+		})
+	})
+}
+
+// Indexes 返回集合的所有索引
+func (c *Client) Indexes(database, collection string) (indexes []Index, err error) {
+	defer c.observe(&err, "Indexes", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return nil, err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	var list []mgo.Index
+	err = c.withRetry(session, func() error {
+		var err error
+		list, err = conn.Indexes()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	indexes = make([]Index, len(list))
+	for i, index := range list {
+		indexes[i] = Index{
+			Key:           index.Key,
+			Unique:        index.Unique,
+			Background:    index.Background,
+			Sparse:        index.Sparse,
+			ExpireAfter:   index.ExpireAfter,
+			Name:          index.Name,
+			PartialFilter: index.PartialFilter,
+		}
+	}
+	return indexes, nil
+}
+
+// DropIndex 根据索引字段删除索引
+func (c *Client) DropIndex(database, collection string, key ...string) (err error) {
+	defer c.observe(&err, "DropIndex", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	return c.withRetry(session, func() error {
+		return conn.DropIndex(key...)
+	})
+}
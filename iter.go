@@ -0,0 +1,103 @@
+package mongo
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo"
+)
+
+// Iter 游标迭代器,session在Close前一直保持打开,使用完必须调用Close
+type Iter struct {
+	session *mgo.Session
+	iter    *mgo.Iter
+}
+
+// Iter 返回查询结果的游标迭代器,用于流式读取大结果集
+func (c *Client) Iter(database, collection string, query M, opts FindOptions) (iter *Iter, err error) {
+	defer c.observe(&err, "Iter", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return nil, err
+	}
+	session := c.session.Copy()
+	conn := session.DB(database).C(collection)
+	if useFindCommand(opts) {
+		return &Iter{session: session, iter: findIter(session, conn, query, nil, opts)}, nil
+	}
+	find := conn.Find(query)
+	applyFindOptions(find, opts)
+	return &Iter{session: session, iter: find.Iter()}, nil
+}
+
+// PipeIter 返回聚合管道结果的游标迭代器,用于流式读取大结果集
+func (c *Client) PipeIter(database, collection string, pipeline []M, opts PipeOptions) (iter *Iter, err error) {
+	defer c.observe(&err, "PipeIter", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return nil, err
+	}
+	session := c.session.Copy()
+	conn := session.DB(database).C(collection)
+	pipe := conn.Pipe(commentPipeline(pipeline, opts.Comment))
+	applyPipeOptions(pipe, opts)
+	return &Iter{session: session, iter: pipe.Iter()}, nil
+}
+
+// Next 读取下一条数据到result,没有数据或出错时返回false
+func (it *Iter) Next(result interface{}) bool {
+	return it.iter.Next(result)
+}
+
+// Err 返回迭代过程中的错误
+func (it *Iter) Err() error {
+	return maxTimeError(it.iter.Err())
+}
+
+// Close 关闭游标并释放session
+func (it *Iter) Close() error {
+	err := it.iter.Close()
+	it.session.Close()
+	return maxTimeError(err)
+}
+
+// tailRetryDelay 集合为空或没有匹配数据时游标会立即失效,重新查询前等待的时间
+const tailRetryDelay = time.Second
+
+// Tail 跟踪集合中新插入的数据,每条数据调用一次handler,集合必须为capped集合
+// 游标失效时从最后一条数据的_id重新查询,集合为空或没有匹配数据时每隔tailRetryDelay重新查询
+// handler返回错误时停止跟踪并返回该错误
+func (c *Client) Tail(database, collection string, query M, handler func(result M) error) (err error) {
+	defer c.observe(&err, "Tail", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	var lastID interface{}
+	iter := conn.Find(query).Sort("$natural").Tail(5 * time.Second)
+	for {
+		var result M
+		for iter.Next(&result) {
+			lastID = result["_id"]
+			if err := handler(result); err != nil {
+				iter.Close()
+				return err
+			}
+			result = nil
+		}
+		if err := iter.Err(); err != nil {
+			iter.Close()
+			return err
+		}
+		if iter.Timeout() {
+			continue
+		}
+		iter.Close()
+		//游标没有超时就失效,说明没有可跟踪的数据,等待后再查询,避免频繁请求服务端
+		time.Sleep(tailRetryDelay)
+		find := query
+		if lastID != nil {
+			find = afterQuery(query, lastID)
+		}
+		iter = conn.Find(find).Sort("$natural").Tail(5 * time.Second)
+	}
+}
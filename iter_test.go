@@ -0,0 +1,98 @@
+package mongo
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func BenchmarkIterPrefetch(b *testing.B) {
+	const n = 100000
+	c := testClient(b)
+	coll := testSeed(b, c, "bench_prefetch", n)
+	for _, prefetch := range []float64{0.25, 0.75} {
+		b.Run(fmt.Sprintf("Prefetch%.2f", prefetch), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				iter, err := c.Iter(testDB, coll, nil, FindOptions{BatchSize: 1000, Prefetch: prefetch})
+				if err != nil {
+					b.Fatal(err)
+				}
+				var doc M
+				count := 0
+				for iter.Next(&doc) {
+					count++
+				}
+				if err := iter.Close(); err != nil {
+					b.Fatal(err)
+				}
+				if count != n {
+					b.Fatalf("read %d docs, want %d", count, n)
+				}
+			}
+		})
+	}
+}
+
+func TestTail(t *testing.T) {
+	c := testClient(t)
+	coll := "tail"
+	if err := c.DropCollection(testDB, coll); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CreateCollection(testDB, coll, CollectionInfo{Capped: true, MaxBytes: 1 << 20}); err != nil {
+		t.Fatal(err)
+	}
+	//集合为空时开始跟踪,之后插入的数据也要能收到
+	time.AfterFunc(200*time.Millisecond, func() {
+		if err := c.Insert(testDB, coll, M{"n": 1}, M{"n": 2}); err != nil {
+			t.Error(err)
+		}
+	})
+	errStop := errors.New("stop")
+	var seen []interface{}
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Tail(testDB, coll, nil, func(result M) error {
+			seen = append(seen, result["n"])
+			if len(seen) == 2 {
+				return errStop
+			}
+			return nil
+		})
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, errStop) {
+			t.Fatalf("Tail = %v, want handler error", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Tail did not stop after the handler error")
+	}
+	if len(seen) != 2 || seen[0] != 1 || seen[1] != 2 {
+		t.Fatalf("seen = %v, want [1 2]", seen)
+	}
+}
+
+func TestIterMaxTimeExceeded(t *testing.T) {
+	c := testClient(t)
+	coll := testSeed(t, c, "iter_max_time", 100)
+	//每条数据执行约5ms,第一批返回后后续的getMore累计超过maxTimeMS被服务端终止
+	slow := M{"$where": "sleep(5) || true"}
+	iter, err := c.Iter(testDB, coll, slow, FindOptions{BatchSize: 2, MaxTimeMS: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iter.Close()
+	var doc M
+	read := 0
+	for iter.Next(&doc) {
+		read++
+	}
+	if read == 0 || read == 100 {
+		t.Fatalf("read %d docs, want the stream to stop midway", read)
+	}
+	if err := iter.Err(); !errors.Is(err, ErrMaxTimeExceeded) {
+		t.Fatalf("Err = %v, want ErrMaxTimeExceeded", err)
+	}
+}
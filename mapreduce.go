@@ -0,0 +1,52 @@
+package mongo
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo"
+)
+
+// MapReduceJob MapReduce任务
+type MapReduceJob struct {
+	Map      string      //map函数,JavaScript代码
+	Reduce   string      //reduce函数,JavaScript代码
+	Finalize string      //finalize函数,可为空
+	Out      interface{} //为空时结果内联返回到result,否则为输出集合,如M{"replace": "results"}
+}
+
+// MapReduceInfo MapReduce执行结果统计
+type MapReduceInfo struct {
+	InputCount  int    //输入的数据条数
+	EmitCount   int    //emit调用次数
+	OutputCount int    //输出的数据条数
+	Database    string //输出的数据库,内联返回时为空
+	Collection  string //输出的集合,内联返回时为空
+}
+
+// MapReduce 对查询结果执行MapReduce,内联返回时结果解码到result
+func (c *Client) MapReduce(database, collection string, job MapReduceJob, query M, result interface{}) (info MapReduceInfo, err error) {
+	defer c.observe(&err, "MapReduce", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return MapReduceInfo{}, err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	mr := &mgo.MapReduce{Map: job.Map, Reduce: job.Reduce, Finalize: job.Finalize, Out: job.Out}
+	var mrInfo *mgo.MapReduceInfo
+	err = c.withRetry(session, func() error {
+		var err error
+		mrInfo, err = conn.Find(query).MapReduce(mr, result)
+		return err
+	})
+	if err != nil {
+		return MapReduceInfo{}, err
+	}
+	return MapReduceInfo{
+		InputCount:  mrInfo.InputCount,
+		EmitCount:   mrInfo.EmitCount,
+		OutputCount: mrInfo.OutputCount,
+		Database:    mrInfo.Database,
+		Collection:  mrInfo.Collection,
+	}, nil
+}
@@ -1,19 +1,50 @@
 package mongo
 
 import (
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
+	"reflect"
 	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
-	//ErrNotFound 数据没有找到
+	//ErrNotFound 数据没有找到,方法返回的错误会包装为Error,需用errors.Is(err, ErrNotFound)判断,不能再用==比较
 	ErrNotFound = mgo.ErrNotFound
+	//ErrClosed 客户端已关闭
+	ErrClosed = errors.New("client closed")
+	//ErrNotConnected 客户端未连接
+	ErrNotConnected = errors.New("client not connected")
+	//ErrMaxTimeExceeded 查询超过MaxTimeMS被服务端终止
+	ErrMaxTimeExceeded = errors.New("operation exceeded time limit")
+	//ErrOperationTimeout 操作超过SetOperationTimeout设置的时间
+	ErrOperationTimeout = errors.New("operation timed out")
+	//ErrEmptySelector 开启SetSafeDeletes后更新或删除的条件为空
+	ErrEmptySelector = errors.New("empty selector")
+	//ErrEmptyUpdate 更新内容为空
+	ErrEmptyUpdate = errors.New("empty update")
+	//ErrVersionConflict 乐观锁更新在重试次数内都因版本变化失败
+	ErrVersionConflict = errors.New("version conflict")
+	//ErrJavaScriptDisabled 未通过SetAllowJavaScript开启JavaScript查询
+	ErrJavaScriptDisabled = errors.New("javascript queries disabled")
+	//ErrInvalidLimit 要求limit大于0的方法传入了小于等于0的limit
+	ErrInvalidLimit = errors.New("limit must be positive")
 )
 
+// DefaultSocketTimeout 默认socket超时时间
+const DefaultSocketTimeout = 24 * time.Hour
+
+// DefaultDialTimeout ConnWithOptions默认的连接超时时间,与mgo.Dial一致
+const DefaultDialTimeout = 10 * time.Second
+
 // M 自定义bson类型
 type M = bson.M
 
@@ -23,37 +54,284 @@ type D bson.D
 // Sort 自定义排序类型
 type Sort []string
 
+// FindOptions 查询选项
+type FindOptions struct {
+	Sort      Sort
+	Limit     int
+	Skip      int
+	BatchSize int
+	Prefetch  float64 //当前批次剩余比例低于该值时预取下一批次,默认0.25,调大可提高流式读取的吞吐
+	Hint      string  //强制使用的索引,如"name,-age"或索引名称"email_unique",单个带下划线的字段写作"user_id,"
+	MaxTimeMS int
+	Collation *Collation
+	Comment   string //附加到查询的$comment,会出现在system.profile和慢查询日志中
+	Snapshot  bool   //按_id索引遍历,避免游标期间被更新移动的数据重复返回,替代MongoDB 4.0已移除的$snapshot,设置Hint时无效,与Sort同时使用时在内存中排序
+	//允许分片集群中部分分片不可用时返回可用分片的数据,结果可能不完整且不会报错,只用于可以接受缺失数据的场景
+	//只对GetResultOpt和Iter生效
+	AllowPartialResults bool
+}
+
+// ModifyOptions 查找并修改选项
+type ModifyOptions struct {
+	Upsert    bool
+	ReturnNew bool
+	Sort      Sort
+	Fields    M
+}
+
+// Collation 自定义排序规则类型,如&Collation{Locale: "en", Strength: 2}表示忽略大小写
+type Collation = mgo.Collation
+
+// PipeOptions 聚合管道选项
+type PipeOptions struct {
+	AllowDiskUse bool
+	BatchSize    int
+	MaxTimeMS    int
+	Collation    *Collation
+	Comment      string //以{$match: {$comment: ...}}阶段插入到管道最前面,不能用于第一阶段必须为$geoNear等的管道
+}
+
+// Mode 自定义读模式类型
+type Mode = mgo.Mode
+
+// 读模式
+const (
+	Primary            = mgo.Primary
+	PrimaryPreferred   = mgo.PrimaryPreferred
+	Secondary          = mgo.Secondary
+	SecondaryPreferred = mgo.SecondaryPreferred
+	Nearest            = mgo.Nearest
+	Eventual           = mgo.Eventual
+	Monotonic          = mgo.Monotonic
+	Strong             = mgo.Strong
+)
+
+// ReadPreference 自定义读偏好类型
+type ReadPreference = mgo.ReadPreference
+
+// ConnOptions 连接选项
+type ConnOptions struct {
+	Addrs          []string
+	Database       string
+	ReplicaSetName string
+	Source         string
+	Mechanism      string
+	Username       string
+	Password       string
+	Timeout        time.Duration //连接和等待可用节点的超时时间,为0时使用DefaultDialTimeout
+	SocketTimeout  time.Duration //为0时使用DefaultSocketTimeout
+	PoolLimit      int           //每个节点的连接池大小,为0时使用mgo默认值4096
+	ReadPreference *ReadPreference
+	TLSConfig      *tls.Config
+}
+
 // ObjectID 自定义ObjectID类型
 type ObjectID = bson.ObjectId
 
 // Client mongodb连接结构体
 type Client struct {
-	host    string
-	session *mgo.Session
-	connErr error
+	host        string
+	session     *mgo.Session
+	connErr     error
+	retries     int
+	backoff     time.Duration
+	retryWrites bool
+	dial        func() (*mgo.Session, error)
+	observer    func(QueryEvent)
+	safeDeletes bool
+	tracer      trace.Tracer
+	sockTimeout time.Duration
+	allowJS     bool
+	opTimeout   time.Duration
+	//Set*方法保存的设置,连接或WaitReady重连后应用到新的session
+	poolLimit int
+	mode      *Mode
+	tagSets   []bson.D
+	safe      *mgo.Safe
 }
 
-// Conn 连接mongodb
+// Conn 连接mongodb,连接失败时错误保存在Client中,可通过Ping获取
 func Conn(urlAddr string) *Client {
+	cli, _ := ConnE(urlAddr)
+	return cli
+}
+
+// ConnE 连接mongodb并直接返回连接错误,支持mongodb+srv://地址
+func ConnE(urlAddr string) (*Client, error) {
 	//[mongodb://][user:pass@]host1[:port1][,host2[:port2],...][/database][?options]
-	cli := &Client{}
-	match := regexp.MustCompile(`mongodb://(.*@)?(.*)/`).FindStringSubmatch(urlAddr)
-	var host string
-	if len(match) > 2 {
-		host = match[2]
+	cli := &Client{sockTimeout: DefaultSocketTimeout}
+	if strings.HasPrefix(urlAddr, srvScheme) {
+		cli.host = srvHost(urlAddr)
+	} else if match := regexp.MustCompile(`mongodb://(.*@)?(.*)/`).FindStringSubmatch(urlAddr); len(match) > 2 {
+		cli.host = match[2]
+	}
+	cli.dial = func() (*mgo.Session, error) {
+		addr := urlAddr
+		if strings.HasPrefix(addr, srvScheme) {
+			var err error
+			if addr, err = resolveSRV(addr); err != nil {
+				return nil, err
+			}
+		}
+		return mgo.Dial(addr)
+	}
+	return cli, cli.connect()
+}
+
+// ConnWithOptions 按连接选项连接mongodb,支持TLS、认证和超时设置
+func ConnWithOptions(opts ConnOptions) (*Client, error) {
+	cli := &Client{host: strings.Join(opts.Addrs, ",")}
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = DefaultDialTimeout
+	}
+	info := &mgo.DialInfo{
+		Addrs:          opts.Addrs,
+		Database:       opts.Database,
+		ReplicaSetName: opts.ReplicaSetName,
+		Source:         opts.Source,
+		Mechanism:      opts.Mechanism,
+		Username:       opts.Username,
+		Password:       opts.Password,
+		Timeout:        timeout,
+		PoolLimit:      opts.PoolLimit,
+		ReadPreference: opts.ReadPreference,
+	}
+	if opts.TLSConfig != nil {
+		info.DialServer = func(addr *mgo.ServerAddr) (net.Conn, error) {
+			return tls.Dial("tcp", addr.String(), opts.TLSConfig)
+		}
+	}
+	socketTimeout := opts.SocketTimeout
+	if socketTimeout == 0 {
+		socketTimeout = DefaultSocketTimeout
+	}
+	cli.sockTimeout = socketTimeout
+	cli.dial = func() (*mgo.Session, error) {
+		session, err := mgo.DialWithInfo(info)
+		if err != nil {
+			return nil, err
+		}
+		session.SetSyncTimeout(timeout)
+		return session, nil
 	}
-	session, err := mgo.Dial(urlAddr)
+	return cli, cli.connect()
+}
+
+// connect 建立连接,失败时错误保存在connErr中
+func (c *Client) connect() error {
+	session, err := c.dial()
 	if err != nil {
-		cli.connErr = fmt.Errorf("host: %s error: %s", host, err.Error())
-		return cli
+		c.connErr = c.hostError(err)
+		return c.connErr
 	}
-	session.SetSocketTimeout(24 * time.Hour)
+	c.applySettings(session)
+	c.session = session
+	c.connErr = nil
+	return nil
+}
 
-	// Optional. Switch the session to a monotonic behavior.
-	//session.SetMode(mgo.Monotonic, true)
-	cli.host = host
-	cli.session = session
-	return cli
+// applySettings 将Set*方法保存的设置应用到session
+func (c *Client) applySettings(session *mgo.Session) {
+	session.SetSocketTimeout(c.sockTimeout)
+	if c.poolLimit > 0 {
+		session.SetPoolLimit(c.poolLimit)
+	}
+	if c.tagSets != nil {
+		session.SelectServers(c.tagSets...)
+	}
+	if c.mode != nil {
+		session.SetMode(*c.mode, true)
+	}
+	if c.safe != nil {
+		session.SetSafe(c.safe)
+	}
+}
+
+// SetSocketTimeout 设置socket超时时间,默认为DefaultSocketTimeout
+// Set*方法在未连接时调用也会保存,WaitReady连接成功后生效
+func (c *Client) SetSocketTimeout(d time.Duration) {
+	c.sockTimeout = d
+	if c.session != nil {
+		c.session.SetSocketTimeout(d)
+	}
+}
+
+// SetOperationTimeout 设置每次操作的超时时间,超时返回ErrOperationTimeout,为0时关闭,ctx方法以ctx截止时间为准
+// 查询、统计、去重和聚合通过maxTimeMS由服务端在超时后终止,超时前失败的请求只在截止时间内重试
+// 写操作和Ping不支持maxTimeMS,超时后立即返回,mgo无法中断已发出的写操作,它仍可能在服务端完成
+// 流式读取的Iter、PipeIter、Tail、Watch、导出和复制时的读取不受影响,socket超时仍由SetSocketTimeout设置
+func (c *Client) SetOperationTimeout(d time.Duration) {
+	c.opTimeout = d
+}
+
+// SetAllowJavaScript 设置是否允许GetResultWhere执行$where JavaScript查询,默认不允许
+func (c *Client) SetAllowJavaScript(on bool) {
+	c.allowJS = on
+}
+
+// SetPoolLimit 设置每个节点的连接池大小,连接用尽时操作会阻塞等待空闲连接
+func (c *Client) SetPoolLimit(limit int) {
+	c.poolLimit = limit
+	if c.session != nil {
+		c.session.SetPoolLimit(limit)
+	}
+}
+
+// SetMode 设置读模式,之后复制的session都继承该模式
+func (c *Client) SetMode(mode Mode, refresh bool) {
+	c.mode = &mode
+	if c.session != nil {
+		c.session.SetMode(mode, refresh)
+	}
+}
+
+// SetReadPreference 设置读模式和节点标签,读操作只会发送到匹配任一组标签的节点
+// 如SetReadPreference(Secondary, []map[string]string{{"region": "us-east"}}),标签对Primary模式无效
+func (c *Client) SetReadPreference(mode Mode, tags []map[string]string) {
+	tagSets := make([]bson.D, len(tags))
+	for i, tag := range tags {
+		keys := make([]string, 0, len(tag))
+		for key := range tag {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			tagSets[i] = append(tagSets[i], bson.DocElem{Name: key, Value: tag[key]})
+		}
+	}
+	c.tagSets = tagSets
+	c.mode = &mode
+	if c.session != nil {
+		c.session.SelectServers(tagSets...)
+		c.session.SetMode(mode, true)
+	}
+}
+
+// SetSafe 设置写关注,之后复制的session的写操作都使用该设置
+func (c *Client) SetSafe(w int, wmode string, j bool, wtimeout time.Duration) {
+	c.safe = &mgo.Safe{W: w, WMode: wmode, J: j, WTimeout: int(wtimeout / time.Millisecond)}
+	if c.session != nil {
+		c.session.SetSafe(c.safe)
+	}
+}
+
+// SetMajority 设置写操作需要多数节点确认并写入日志
+func (c *Client) SetMajority() {
+	c.SetSafe(0, "majority", true, 0)
+}
+
+// SetSafeDeletes 开启后更新和删除方法(包括Context方法、FindAndModify、FindAndRemove和Bulk的Update、Remove)的条件为空时返回ErrEmptySelector,避免误改或误删整个集合
+func (c *Client) SetSafeDeletes(on bool) {
+	c.safeDeletes = on
+}
+
+// checkSelector 开启SetSafeDeletes时检查条件不能为空
+func (c *Client) checkSelector(selector M) error {
+	if c.safeDeletes && len(selector) == 0 {
+		return ErrEmptySelector
+	}
+	return nil
 }
 
 // NewObjectID 返回一个新的唯一ObjectId
@@ -76,111 +354,841 @@ func ObjectIDHex(s string) ObjectID {
 	return bson.ObjectIdHex(s)
 }
 
+// ObjectIDFromTime 返回指定时间的ObjectId,仅用于按_id时间范围查询,如M{"_id": M{"$gte": ObjectIDFromTime(t)}}
+func ObjectIDFromTime(t time.Time) ObjectID {
+	return bson.NewObjectIdWithTime(t)
+}
+
+// TimeFromObjectID 返回ObjectId中的创建时间,精确到秒
+func TimeFromObjectID(oid ObjectID) time.Time {
+	return oid.Time()
+}
+
+// IsDup 返回错误是否为唯一索引冲突,支持方法返回的Error
+func IsDup(err error) bool {
+	return mgo.IsDup(errorCause(err))
+}
+
 // Ping 监测数据库连接
-func (c *Client) Ping() error {
-	if c.connErr != nil {
-		return c.connErr
+func (c *Client) Ping() (err error) {
+	defer c.observe(&err, "Ping", "", "", time.Now())
+	if err := c.ready(); err != nil {
+		return err
 	}
 	session := c.session.Copy()
 	defer session.Close()
-	err := session.Ping()
+	err = c.bounded(session, session.Ping)
+	if err == ErrOperationTimeout {
+		return err
+	}
 	if err != nil {
-		c.connErr = fmt.Errorf("host: %s error: %s", c.host, err.Error())
+		c.connErr = c.hostError(err)
 	}
 	return c.connErr
 }
 
-// GetRow 返回一行数据
-func (c *Client) GetRow(database, collection string, query, options M, result interface{}) error {
+// Close 关闭连接并释放session,之后的操作返回ErrClosed,可重复调用
+func (c *Client) Close() {
+	if c.session != nil {
+		c.session.Close()
+		c.session = nil
+	}
+	c.connErr = ErrClosed
+}
+
+// Session 返回底层mgo session的副本,用于本库未封装的操作,客户端不可用时返回nil
+// 返回的session由调用方负责,使用完必须调用Close
+func (c *Client) Session() *mgo.Session {
+	if c.ready() != nil {
+		return nil
+	}
+	return c.session.Copy()
+}
+
+// ready 检查客户端是否可用
+func (c *Client) ready() error {
 	if c.connErr != nil {
 		return c.connErr
 	}
+	if c.session == nil {
+		return ErrNotConnected
+	}
+	return nil
+}
+
+// GetRow 返回一行数据,不存在报ErrNotFound,用errors.Is判断
+func (c *Client) GetRow(database, collection string, query, options M, result interface{}) (err error) {
+	defer c.observe(&err, "GetRow", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return err
+	}
 	session := c.session.Copy()
 	defer session.Close()
 	conn := session.DB(database).C(collection)
-	find := conn.Find(query)
+	//按名称指定索引
+	if opts := findOptions(options); useFindCommand(opts) {
+		opts = FindOptions{Sort: opts.Sort, Hint: opts.Hint, Limit: 1}
+		if c.opTimeout > 0 {
+			opts.MaxTimeMS = int(c.opTimeout / time.Millisecond)
+		}
+		return c.withRetry(session, func() error {
+			return iterOne(findIter(session, conn, query, nil, opts), result)
+		})
+	}
+	find := c.find(conn, query)
 	//排序
 	if options["Sort"] != "" {
 		if sort, ok := options["Sort"].(Sort); ok {
 			find.Sort(sort...)
 		}
 	}
-	return find.One(result)
+	//索引
+	if hint, ok := options["Hint"].(string); ok && hint != "" {
+		find.Hint(queryHint(hint)...)
+	}
+	return c.withRetry(session, func() error {
+		return find.One(result)
+	})
+}
+
+// GetById 根据_id返回一行数据,不存在报ErrNotFound,用errors.Is判断
+func (c *Client) GetById(database, collection string, id ObjectID, result interface{}) error {
+	return c.GetRow(database, collection, M{"_id": id}, nil, result)
 }
 
 // GetResult 返回多行结果集
 func (c *Client) GetResult(database, collection string, query, fields, options M, result interface{}) error {
-	if c.connErr != nil {
-		return c.connErr
+	return c.GetResultOpt(database, collection, query, fields, findOptions(options), result)
+}
+
+// GetResultOpt 按查询选项返回多行结果集
+func (c *Client) GetResultOpt(database, collection string, query, fields M, opts FindOptions, result interface{}) (err error) {
+	defer c.observe(&err, "GetResultOpt", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return err
 	}
 	session := c.session.Copy()
 	defer session.Close()
 	conn := session.DB(database).C(collection)
-	find := conn.Find(query).Select(fields)
-	//排序
-	if options["Sort"] != "" {
-		if sort, ok := options["Sort"].(Sort); ok {
-			find.Sort(sort...)
+	if useFindCommand(opts) {
+		if opts.MaxTimeMS == 0 && c.opTimeout > 0 {
+			opts.MaxTimeMS = int(c.opTimeout / time.Millisecond)
 		}
+		return maxTimeError(c.withRetry(session, func() error {
+			return findIter(session, conn, query, fields, opts).All(result)
+		}))
 	}
-	//分页
-	if options["Limit"] != "" {
-		if limit, ok := options["Limit"].(int); ok {
-			find.Limit(limit)
+	find := c.find(conn, query).Select(fields)
+	applyFindOptions(find, opts)
+	return maxTimeError(c.withRetry(session, func() error {
+		return find.All(result)
+	}))
+}
+
+// GetResultWhere 使用$where JavaScript条件返回多行结果集,需要先调用SetAllowJavaScript(true)开启
+// $where对每条数据执行JavaScript且无法使用索引,速度很慢,js不能拼接用户输入,否则会导致注入,服务端可能禁用了JavaScript
+func (c *Client) GetResultWhere(database, collection string, js string, opts FindOptions, result interface{}) error {
+	if !c.allowJS {
+		return ErrJavaScriptDisabled
+	}
+	return c.GetResultOpt(database, collection, M{"$where": js}, nil, opts, result)
+}
+
+// GetResultSingleBatch 返回最多limit行结果集,批次大小等于limit,结果在一次网络往返中返回
+// limit小于等于0时返回ErrInvalidLimit,不会退化为读取整个集合
+func (c *Client) GetResultSingleBatch(database, collection string, query, fields M, limit int, result interface{}) error {
+	if limit <= 0 {
+		return ErrInvalidLimit
+	}
+	return c.GetResultOpt(database, collection, query, fields, FindOptions{Limit: limit, BatchSize: limit}, result)
+}
+
+// GetRawResult 按查询选项返回未解码的多行结果集,可通过Unmarshal按需解码为不同类型
+func (c *Client) GetRawResult(database, collection string, query M, opts FindOptions) ([]bson.Raw, error) {
+	var result []bson.Raw
+	if err := c.GetResultOpt(database, collection, query, nil, opts, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Explain 返回查询的执行计划,可用于判断查询是否使用了索引(IXSCAN)或全表扫描(COLLSCAN)
+func (c *Client) Explain(database, collection string, query M, opts FindOptions, result interface{}) (err error) {
+	defer c.observe(&err, "Explain", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	if useFindCommand(opts) {
+		if opts.MaxTimeMS == 0 && c.opTimeout > 0 {
+			opts.MaxTimeMS = int(c.opTimeout / time.Millisecond)
 		}
+		cmd := bson.D{{Name: "explain", Value: findCommand(conn, query, nil, opts)}}
+		return c.withRetry(session, func() error {
+			return conn.Database.Run(cmd, result)
+		})
+	}
+	find := c.find(conn, query)
+	applyFindOptions(find, opts)
+	return c.withRetry(session, func() error {
+		return find.Explain(result)
+	})
+}
+
+// GetResultWithTotal 按查询选项返回多行结果集和匹配总条数
+// 统计和查询是两次请求,但在同一个session中执行
+func (c *Client) GetResultWithTotal(database, collection string, query, fields M, opts FindOptions, result interface{}) (total int, err error) {
+	defer c.observe(&err, "GetResultWithTotal", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return 0, err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	err = c.withRetry(session, func() error {
+		var err error
+		total, err = c.find(conn, query).Count()
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	find := c.find(conn, query).Select(fields)
+	applyFindOptions(find, opts)
+	return total, maxTimeError(c.withRetry(session, func() error {
+		return find.All(result)
+	}))
+}
+
+// GetResultAfter 按_id游标分页返回多行结果集,afterID为空时返回第一页
+// 返回本页最后一条数据的_id作为下一页的afterID,没有数据时返回空
+func (c *Client) GetResultAfter(database, collection string, query M, afterID ObjectID, limit int, result interface{}) (nextID ObjectID, err error) {
+	defer c.observe(&err, "GetResultAfter", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return "", err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	if afterID != "" {
+		query = afterQuery(query, afterID)
+	}
+	err = c.withRetry(session, func() error {
+		return c.find(conn, query).Sort("_id").Limit(limit).All(result)
+	})
+	if err != nil {
+		return "", err
+	}
+	rows := reflect.Indirect(reflect.ValueOf(result))
+	if rows.Kind() != reflect.Slice || rows.Len() == 0 {
+		return "", nil
+	}
+	data, err := bson.Marshal(rows.Index(rows.Len() - 1).Interface())
+	if err != nil {
+		return "", err
+	}
+	var last struct {
+		ID ObjectID `bson:"_id"`
+	}
+	err = bson.Unmarshal(data, &last)
+	return last.ID, err
+}
+
+// afterQuery 在查询条件上追加_id大于id的条件
+func afterQuery(query M, id interface{}) M {
+	after := M{"_id": M{"$gt": id}}
+	if len(query) == 0 {
+		return after
+	}
+	return M{"$and": []M{query, after}}
+}
+
+// findOptions 将M形式的选项转换为FindOptions
+func findOptions(options M) FindOptions {
+	var opts FindOptions
+	//排序
+	if sort, ok := options["Sort"].(Sort); ok {
+		opts.Sort = sort
+	}
+	//分页
+	if limit, ok := options["Limit"].(int); ok {
+		opts.Limit = limit
 	}
 	//跳过
-	if options["Skip"] != "" {
-		if skip, ok := options["Skip"].(int); ok {
-			find.Skip(skip)
+	if skip, ok := options["Skip"].(int); ok {
+		opts.Skip = skip
+	}
+	//索引
+	if hint, ok := options["Hint"].(string); ok {
+		opts.Hint = hint
+	}
+	return opts
+}
+
+// find 返回conn上的查询,设置了SetOperationTimeout时由服务端按maxTimeMS在超时后终止
+// 流式读取的Iter、Tail、Watch、导出和复制不使用该方法,不受操作超时限制
+func (c *Client) find(conn *mgo.Collection, query interface{}) *mgo.Query {
+	find := conn.Find(query)
+	if c.opTimeout > 0 {
+		find.SetMaxTime(c.opTimeout)
+	}
+	return find
+}
+
+// pipe 返回conn上的聚合管道,设置了SetOperationTimeout时由服务端按maxTimeMS在超时后终止
+func (c *Client) pipe(conn *mgo.Collection, pipeline interface{}) *mgo.Pipe {
+	pipe := conn.Pipe(pipeline)
+	if c.opTimeout > 0 {
+		pipe.SetMaxTime(c.opTimeout)
+	}
+	return pipe
+}
+
+// applyFindOptions 将查询选项应用到查询上
+func applyFindOptions(find *mgo.Query, opts FindOptions) {
+	if len(opts.Sort) > 0 {
+		find.Sort(opts.Sort...)
+	}
+	if opts.Limit > 0 {
+		find.Limit(opts.Limit)
+	}
+	if opts.Skip > 0 {
+		find.Skip(opts.Skip)
+	}
+	if opts.BatchSize > 0 {
+		find.Batch(opts.BatchSize)
+	}
+	if opts.Prefetch > 0 {
+		find.Prefetch(opts.Prefetch)
+	}
+	if opts.Hint != "" {
+		find.Hint(queryHint(opts.Hint)...)
+	} else if opts.Snapshot {
+		find.Hint("_id")
+	}
+	if opts.MaxTimeMS > 0 {
+		find.SetMaxTime(time.Duration(opts.MaxTimeMS) * time.Millisecond)
+	}
+	if opts.Collation != nil {
+		find.Collation(opts.Collation)
+	}
+	if opts.Comment != "" {
+		find.Comment(opts.Comment)
+	}
+}
+
+// hintKeys 将Hint解析为索引字段,支持逗号分隔的字段如"name,-age"、单个字段如"name"
+// 和默认生成的索引名称如"name_1_age_-1",其他带下划线的值视为自定义索引名称,返回nil
+func hintKeys(hint string) []string {
+	if strings.Contains(hint, ",") {
+		var keys []string
+		for _, key := range strings.Split(hint, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				keys = append(keys, key)
+			}
 		}
+		return keys
+	}
+	if !strings.Contains(hint, "_") {
+		return []string{hint}
+	}
+	var keys, field []string
+	for _, part := range strings.Split(hint, "_") {
+		if (part == "1" || part == "-1") && len(field) > 0 {
+			key := strings.Join(field, "_")
+			if part == "-1" {
+				key = "-" + key
+			}
+			keys = append(keys, key)
+			field = nil
+			continue
+		}
+		field = append(field, part)
+	}
+	if len(keys) == 0 || len(field) > 0 {
+		return nil
+	}
+	return keys
+}
+
+// useFindCommand 判断查询是否需要直接执行find命令,mgo的Query不支持allowPartialResults和按名称指定索引
+func useFindCommand(opts FindOptions) bool {
+	return opts.AllowPartialResults || (opts.Hint != "" && hintKeys(opts.Hint) == nil)
+}
+
+// queryHint 返回mgo的Query使用的索引字段,自定义索引名称只在GetResult、GetRow、Iter、Explain及其Context方法中按名称发送
+// 其他方法中原样作为字段传入,由服务端报告索引不存在
+func queryHint(hint string) []string {
+	if keys := hintKeys(hint); keys != nil {
+		return keys
+	}
+	return []string{hint}
+}
+
+// iterOne 读取iter的第一条数据到result,没有数据时返回ErrNotFound
+func iterOne(iter *mgo.Iter, result interface{}) error {
+	if iter.Next(result) {
+		return iter.Close()
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+	return ErrNotFound
+}
+
+// findIter 执行find命令并返回游标,用于useFindCommand为true的查询
+func findIter(session *mgo.Session, conn *mgo.Collection, query, fields M, opts FindOptions) *mgo.Iter {
+	//游标只能在创建它的节点上读取
+	if session.Mode() == mgo.Eventual {
+		session.SetMode(mgo.Monotonic, false)
+	}
+	if opts.BatchSize > 0 {
+		session.SetBatch(opts.BatchSize)
+	}
+	if opts.Prefetch > 0 {
+		session.SetPrefetch(opts.Prefetch)
 	}
-	return find.All(result)
+	var result struct {
+		Cursor struct {
+			FirstBatch []bson.Raw `bson:"firstBatch"`
+			ID         int64      `bson:"id"`
+		} `bson:"cursor"`
+	}
+	err := conn.Database.Run(findCommand(conn, query, fields, opts), &result)
+	return conn.NewIter(session, result.Cursor.FirstBatch, result.Cursor.ID, err)
+}
+
+// findCommand 按查询选项生成find命令
+func findCommand(conn *mgo.Collection, query, fields M, opts FindOptions) bson.D {
+	cmd := bson.D{{Name: "find", Value: conn.Name}, {Name: "filter", Value: query}}
+	if fields != nil {
+		cmd = append(cmd, bson.DocElem{Name: "projection", Value: fields})
+	}
+	if len(opts.Sort) > 0 {
+		cmd = append(cmd, bson.DocElem{Name: "sort", Value: keysDoc(opts.Sort)})
+	}
+	if opts.Skip > 0 {
+		cmd = append(cmd, bson.DocElem{Name: "skip", Value: opts.Skip})
+	}
+	if opts.Limit > 0 {
+		cmd = append(cmd, bson.DocElem{Name: "limit", Value: opts.Limit})
+	}
+	if opts.BatchSize > 0 {
+		cmd = append(cmd, bson.DocElem{Name: "batchSize", Value: opts.BatchSize})
+	}
+	if opts.Hint != "" {
+		var hint interface{} = opts.Hint
+		if keys := hintKeys(opts.Hint); keys != nil {
+			hint = keysDoc(keys)
+		}
+		cmd = append(cmd, bson.DocElem{Name: "hint", Value: hint})
+	} else if opts.Snapshot {
+		cmd = append(cmd, bson.DocElem{Name: "hint", Value: bson.D{{Name: "_id", Value: 1}}})
+	}
+	if opts.MaxTimeMS > 0 {
+		cmd = append(cmd, bson.DocElem{Name: "maxTimeMS", Value: opts.MaxTimeMS})
+	}
+	if opts.Collation != nil {
+		cmd = append(cmd, bson.DocElem{Name: "collation", Value: opts.Collation})
+	}
+	if opts.Comment != "" {
+		cmd = append(cmd, bson.DocElem{Name: "comment", Value: opts.Comment})
+	}
+	if opts.AllowPartialResults {
+		cmd = append(cmd, bson.DocElem{Name: "allowPartialResults", Value: true})
+	}
+	return cmd
+}
+
+// keysDoc 将"name"、"-age"、"$textScore:score"形式的字段转换为排序或索引文档
+func keysDoc(keys []string) bson.D {
+	doc := make(bson.D, 0, len(keys))
+	for _, key := range keys {
+		switch {
+		case strings.HasPrefix(key, "$textScore:"):
+			doc = append(doc, bson.DocElem{Name: key[len("$textScore:"):], Value: M{"$meta": "textScore"}})
+		case strings.HasPrefix(key, "-"):
+			doc = append(doc, bson.DocElem{Name: key[1:], Value: -1})
+		default:
+			doc = append(doc, bson.DocElem{Name: strings.TrimPrefix(key, "+"), Value: 1})
+		}
+	}
+	return doc
+}
+
+// maxTimeError 将服务端超时终止的错误转换为ErrMaxTimeExceeded
+func maxTimeError(err error) error {
+	if qerr, ok := err.(*mgo.QueryError); ok && qerr.Code == 50 {
+		return ErrMaxTimeExceeded
+	}
+	if lerr, ok := err.(*mgo.LastError); ok && lerr.Code == 50 {
+		return ErrMaxTimeExceeded
+	}
+	return err
 }
 
 // GetCount 返回统计条数
-func (c *Client) GetCount(database, collection string, query M) (int, error) {
-	if c.connErr != nil {
-		return 0, c.connErr
+func (c *Client) GetCount(database, collection string, query M) (count int, err error) {
+	defer c.observe(&err, "GetCount", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return 0, err
 	}
 	session := c.session.Copy()
 	defer session.Close()
 	conn := session.DB(database).C(collection)
 	//query MongoDB
-	return conn.Find(query).Count()
+	err = c.withRetry(session, func() error {
+		var err error
+		count, err = c.find(conn, query).Count()
+		return err
+	})
+	return count, err
+}
+
+// EstimatedCount 根据集合元数据快速返回数据条数
+// 返回的是估计值,非正常关机或分片集群迁移数据时可能不准确
+func (c *Client) EstimatedCount(database, collection string) (count int, err error) {
+	defer c.observe(&err, "EstimatedCount", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return 0, err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	err = c.withRetry(session, func() error {
+		var err error
+		count, err = conn.Count()
+		return err
+	})
+	return count, err
+}
+
+// CountWithOpts 按查询选项返回统计条数,Skip和Limit会作用于统计,统计结果不超过Limit
+func (c *Client) CountWithOpts(database, collection string, query M, opts FindOptions) (count int, err error) {
+	defer c.observe(&err, "CountWithOpts", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return 0, err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	find := c.find(conn, query)
+	applyFindOptions(find, opts)
+	err = c.withRetry(session, func() error {
+		var err error
+		count, err = find.Count()
+		return err
+	})
+	return count, maxTimeError(err)
+}
+
+// Exists 返回是否存在匹配的数据,只读取一条数据的_id
+func (c *Client) Exists(database, collection string, query M) (exists bool, err error) {
+	defer c.observe(&err, "Exists", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return false, err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	var dummy M
+	err = c.withRetry(session, func() error {
+		return c.find(conn, query).Select(M{"_id": 1}).Limit(1).One(&dummy)
+	})
+	if err == ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Distinct 返回字段的去重值,query为空时对整个集合去重,result为对应类型切片的指针
+func (c *Client) Distinct(database, collection string, key string, query M, result interface{}) (err error) {
+	defer c.observe(&err, "Distinct", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	//mgo的Distinct不支持maxTimeMS,直接执行distinct命令
+	cmd := bson.D{{Name: "distinct", Value: collection}, {Name: "key", Value: key}}
+	if query != nil {
+		cmd = append(cmd, bson.DocElem{Name: "query", Value: query})
+	}
+	if c.opTimeout > 0 {
+		cmd = append(cmd, bson.DocElem{Name: "maxTimeMS", Value: int64(c.opTimeout / time.Millisecond)})
+	}
+	var doc struct {
+		Values bson.Raw `bson:"values"`
+	}
+	err = c.withRetry(session, func() error {
+		return session.DB(database).Run(cmd, &doc)
+	})
+	if err != nil {
+		return err
+	}
+	return doc.Values.Unmarshal(result)
 }
 
 // Insert 插入数据
-func (c *Client) Insert(database, collection string, docs ...interface{}) error {
-	if c.connErr != nil {
-		return c.connErr
+func (c *Client) Insert(database, collection string, docs ...interface{}) (err error) {
+	defer c.observe(&err, "Insert", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	return c.withWrite(session, func() error {
+		return conn.Insert(docs...)
+	})
+}
+
+// InsertBatched 按batchSize分批插入数据,返回已插入的条数,遇到错误时停止
+// 批次之间不是原子操作,出错时之前批次的数据已经插入
+func (c *Client) InsertBatched(database, collection string, batchSize int, docs ...interface{}) (inserted int, err error) {
+	defer c.observe(&err, "InsertBatched", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return 0, err
+	}
+	if batchSize <= 0 {
+		batchSize = len(docs)
 	}
 	session := c.session.Copy()
 	defer session.Close()
 	conn := session.DB(database).C(collection)
-	return conn.Insert(docs...)
+	for start := 0; start < len(docs); start += batchSize {
+		end := start + batchSize
+		if end > len(docs) {
+			end = len(docs)
+		}
+		err = c.withWrite(session, func() error {
+			return conn.Insert(docs[start:end]...)
+		})
+		if err != nil {
+			return inserted, err
+		}
+		inserted = end
+	}
+	return inserted, nil
+}
+
+// InsertReturnIDs 插入数据并按顺序返回每条数据的_id
+// 没有_id的M或结构体指针会预先分配新的ObjectID,已有_id的直接返回
+// 结构体通过bson标签"_id"查找字段,非指针结构体无法赋值会返回错误,_id不是ObjectID类型时返回空值
+func (c *Client) InsertReturnIDs(database, collection string, docs ...interface{}) (ids []ObjectID, err error) {
+	defer wrapError(&err, "InsertReturnIDs", database, collection)
+	ids = make([]ObjectID, len(docs))
+	for i, doc := range docs {
+		id, err := ensureObjectID(doc)
+		if err != nil {
+			return nil, err
+		}
+		ids[i] = id
+	}
+	if err := c.Insert(database, collection, docs...); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// ensureObjectID 返回数据的_id,没有时分配新的ObjectID
+func ensureObjectID(doc interface{}) (ObjectID, error) {
+	if m, ok := doc.(M); ok {
+		if id, ok := m["_id"]; ok {
+			oid, _ := id.(ObjectID)
+			return oid, nil
+		}
+		oid := NewObjectID()
+		m["_id"] = oid
+		return oid, nil
+	}
+	v := reflect.ValueOf(doc)
+	settable := v.Kind() == reflect.Ptr
+	v = reflect.Indirect(v)
+	if v.Kind() != reflect.Struct {
+		return "", fmt.Errorf("unsupported doc type: %T", doc)
+	}
+	for i := 0; i < v.NumField(); i++ {
+		tag := strings.Split(v.Type().Field(i).Tag.Get("bson"), ",")[0]
+		if tag != "_id" || !v.Field(i).CanInterface() {
+			continue
+		}
+		field := v.Field(i)
+		oid, ok := field.Interface().(ObjectID)
+		if !ok || oid != "" {
+			return oid, nil
+		}
+		if !settable || !field.CanSet() {
+			return "", fmt.Errorf("can not set _id of doc type: %T", doc)
+		}
+		oid = NewObjectID()
+		field.Set(reflect.ValueOf(oid))
+		return oid, nil
+	}
+	return "", fmt.Errorf("no _id field in doc type: %T", doc)
 }
 
 // Update 更新数据,不存在报ErrNotFound
-func (c *Client) Update(database, collection string, selector, update M) error {
-	if c.connErr != nil {
-		return c.connErr
+func (c *Client) Update(database, collection string, selector, update M) (err error) {
+	defer c.observe(&err, "Update", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return err
+	}
+	if err := c.checkSelector(selector); err != nil {
+		return err
 	}
 	session := c.session.Copy()
 	defer session.Close()
 	conn := session.DB(database).C(collection)
-	return conn.Update(selector, update)
+	return c.withWrite(session, func() error {
+		return conn.Update(selector, update)
+	})
+}
+
+// UpdateId 根据_id更新数据,不存在报ErrNotFound
+func (c *Client) UpdateId(database, collection string, id ObjectID, update M) error {
+	return c.Update(database, collection, M{"_id": id}, update)
+}
+
+// OptimisticUpdate 基于版本字段的乐观锁更新,读取当前数据后调用mutate生成更新操作,只有版本未变化时才更新并将版本加1
+// 版本变化时重新读取并重试,最多重试maxRetries次后返回ErrVersionConflict,mutate返回的必须是$set等更新操作
+// 其中的$inc可以是M、map[string]interface{}或bson.D,会与版本字段的$inc合并
+func (c *Client) OptimisticUpdate(database, collection string, id ObjectID, versionField string, mutate func(current M) (M, error), maxRetries int) error {
+	for i := 0; i <= maxRetries; i++ {
+		current := M{}
+		if err := c.GetById(database, collection, id, &current); err != nil {
+			return err
+		}
+		version := current[versionField]
+		update, err := mutate(current)
+		if err != nil {
+			return err
+		}
+		versioned, err := versionedUpdate(update, versionField)
+		if err != nil {
+			return err
+		}
+		err = c.Update(database, collection, M{"_id": id, versionField: version}, versioned)
+		if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	return ErrVersionConflict
+}
+
+// versionedUpdate 返回在update的$inc中加上versionField加1的新更新操作,不修改update
+func versionedUpdate(update M, versionField string) (M, error) {
+	inc := M{}
+	switch old := update["$inc"].(type) {
+	case nil:
+	case M:
+		for key, value := range old {
+			inc[key] = value
+		}
+	case map[string]interface{}:
+		for key, value := range old {
+			inc[key] = value
+		}
+	case bson.D:
+		for _, elem := range old {
+			inc[elem.Name] = elem.Value
+		}
+	default:
+		return nil, fmt.Errorf("unsupported $inc type %T", old)
+	}
+	inc[versionField] = 1
+	versioned := make(M, len(update)+1)
+	for key, value := range update {
+		versioned[key] = value
+	}
+	versioned["$inc"] = inc
+	return versioned, nil
+}
+
+// UpdateWithArrayFilters 使用arrayFilters更新一条数据中匹配的数组元素,不存在报ErrNotFound,需要MongoDB 3.6及以上
+// 如update为M{"$inc": M{"grades.$[g].score": 5}},arrayFilters为[]M{{"g.score": M{"$lt": 60}}}
+func (c *Client) UpdateWithArrayFilters(database, collection string, selector, update M, arrayFilters []M) (err error) {
+	defer c.observe(&err, "UpdateWithArrayFilters", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return err
+	}
+	if err := c.checkSelector(selector); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	spec := M{"q": selector, "u": update}
+	if len(arrayFilters) > 0 {
+		spec["arrayFilters"] = arrayFilters
+	}
+	var result updateResult
+	err = c.withWrite(session, func() error {
+		var err error
+		result, err = runUpdate(session, database, collection, []M{spec}, true)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	//未开启写确认时服务端不返回匹配条数
+	if result.N == 0 && session.Safe() != nil {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpdateInfo 更新数据并返回匹配和修改条数,不存在报ErrNotFound
+func (c *Client) UpdateInfo(database, collection string, selector, update M) (changed map[string]interface{}, err error) {
+	defer c.observe(&err, "UpdateInfo", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return map[string]interface{}{}, err
+	}
+	if err := c.checkSelector(selector); err != nil {
+		return map[string]interface{}{}, err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	var info *mgo.BulkResult
+	err = c.withWrite(session, func() error {
+		bulk := conn.Bulk()
+		bulk.Update(selector, update)
+		var err error
+		info, err = bulk.Run()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if info.Matched == 0 {
+		return nil, ErrNotFound
+	}
+	return map[string]interface{}{"Matched": info.Matched, "Updated": info.Modified, "UpsertedId": nil}, nil
 }
 
 // UpdateAll 批量更新数据,不存在报ErrNotFound
-func (c *Client) UpdateAll(database, collection string, selector, update M) (map[string]interface{}, error) {
-	if c.connErr != nil {
-		return map[string]interface{}{}, c.connErr
+func (c *Client) UpdateAll(database, collection string, selector, update M) (changed map[string]interface{}, err error) {
+	defer c.observe(&err, "UpdateAll", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return map[string]interface{}{}, err
+	}
+	if err := c.checkSelector(selector); err != nil {
+		return map[string]interface{}{}, err
 	}
 	session := c.session.Copy()
 	defer session.Close()
 	conn := session.DB(database).C(collection)
-	info, err := conn.UpdateAll(selector, update)
+	var info *mgo.ChangeInfo
+	err = c.withWrite(session, func() error {
+		var err error
+		info, err = conn.UpdateAll(selector, update)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -188,41 +1196,87 @@ func (c *Client) UpdateAll(database, collection string, selector, update M) (map
 }
 
 // Upsert 更新数据,不存在会新插入数据
-func (c *Client) Upsert(database, collection string, selector, update M) (map[string]interface{}, error) {
-	if c.connErr != nil {
-		return map[string]interface{}{}, c.connErr
+func (c *Client) Upsert(database, collection string, selector, update M) (changed map[string]interface{}, err error) {
+	defer c.observe(&err, "Upsert", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return map[string]interface{}{}, err
 	}
 	session := c.session.Copy()
 	defer session.Close()
 	conn := session.DB(database).C(collection)
-	info, err := conn.Upsert(selector, update)
+	var info *mgo.ChangeInfo
+	err = c.withWrite(session, func() error {
+		var err error
+		info, err = conn.Upsert(selector, update)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 	return map[string]interface{}{"Matched": info.Matched, "Updated": info.Updated, "UpsertedId": info.UpsertedId}, nil
 }
 
+// UpsertId 根据_id更新数据,不存在时以该_id新插入数据
+func (c *Client) UpsertId(database, collection string, id ObjectID, update M) (map[string]interface{}, error) {
+	return c.Upsert(database, collection, M{"_id": id}, update)
+}
+
+// UpsertSetOnInsert 更新数据,不存在会新插入数据,always每次都设置,onInsert只在新插入时设置
+// 如always为M{"updated_at": now},onInsert为M{"created_at": now},两者都为空时返回ErrEmptyUpdate
+func (c *Client) UpsertSetOnInsert(database, collection string, selector M, always M, onInsert M) (map[string]interface{}, error) {
+	if len(always) == 0 && len(onInsert) == 0 {
+		return map[string]interface{}{}, ErrEmptyUpdate
+	}
+	update := M{}
+	if len(always) > 0 {
+		update["$set"] = always
+	}
+	if len(onInsert) > 0 {
+		update["$setOnInsert"] = onInsert
+	}
+	return c.Upsert(database, collection, selector, update)
+}
+
 // Remove 删除数据
-func (c *Client) Remove(database, collection string, selector M) error {
-	if c.connErr != nil {
-		return c.connErr
+func (c *Client) Remove(database, collection string, selector M) (err error) {
+	defer c.observe(&err, "Remove", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return err
+	}
+	if err := c.checkSelector(selector); err != nil {
+		return err
 	}
 	session := c.session.Copy()
 	defer session.Close()
 	conn := session.DB(database).C(collection)
-	return conn.Remove(selector)
+	return c.withWrite(session, func() error {
+		return conn.Remove(selector)
+	})
+}
+
+// RemoveId 根据_id删除数据,不存在报ErrNotFound
+func (c *Client) RemoveId(database, collection string, id ObjectID) error {
+	return c.Remove(database, collection, M{"_id": id})
 }
 
 // RemoveAll 批量删除数据
-func (c *Client) RemoveAll(database, collection string, selector M) (int, error) {
-	if c.connErr != nil {
-		return 0, c.connErr
+func (c *Client) RemoveAll(database, collection string, selector M) (removed int, err error) {
+	defer c.observe(&err, "RemoveAll", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return 0, err
+	}
+	if err := c.checkSelector(selector); err != nil {
+		return 0, err
 	}
 	session := c.session.Copy()
 	defer session.Close()
 	conn := session.DB(database).C(collection)
-	info, err := conn.RemoveAll(selector)
-	var removed int
+	var info *mgo.ChangeInfo
+	err = c.withWrite(session, func() error {
+		var err error
+		info, err = conn.RemoveAll(selector)
+		return err
+	})
 	if err == nil {
 		removed = info.Removed
 	}
@@ -230,9 +1284,13 @@ func (c *Client) RemoveAll(database, collection string, selector M) (int, error)
 }
 
 // FindAndModify 查找并修改数据
-func (c *Client) FindAndModify(database, collection string, selector, update M, upsert bool, result interface{}) (int, error) {
-	if c.connErr != nil {
-		return 0, c.connErr
+func (c *Client) FindAndModify(database, collection string, selector, update M, upsert bool, result interface{}) (updated int, err error) {
+	defer c.observe(&err, "FindAndModify", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return 0, err
+	}
+	if err := c.checkSelector(selector); err != nil {
+		return 0, err
 	}
 	session := c.session.Copy()
 	defer func() {
@@ -240,18 +1298,112 @@ func (c *Client) FindAndModify(database, collection string, selector, update M,
 	}()
 	change := mgo.Change{Update: update, Upsert: upsert, ReturnNew: true}
 	conn := session.DB(database).C(collection)
-	info, err := conn.Find(selector).Apply(change, result)
-	var updated int
+	info, err := c.applyChange(session, conn.Find(selector), change, result)
 	if err == nil {
 		updated = info.Updated
 	}
 	return updated, err
 }
 
+// FindAndModifyOpt 按选项查找并修改数据,Sort用于选择修改哪一条数据
+func (c *Client) FindAndModifyOpt(database, collection string, selector M, update M, opts ModifyOptions, result interface{}) (updated int, err error) {
+	defer c.observe(&err, "FindAndModifyOpt", database, collection, time.Now())
+	change := mgo.Change{Update: update, Upsert: opts.Upsert, ReturnNew: opts.ReturnNew}
+	info, err := c.apply(database, collection, selector, change, opts, result)
+	if err != nil {
+		return 0, err
+	}
+	return info.Updated, nil
+}
+
+// UpdateAndGet 更新一条数据并将更新后的数据解码到result,数据不存在时返回false且不报错
+func (c *Client) UpdateAndGet(database, collection string, selector, update M, result interface{}) (found bool, err error) {
+	defer c.observe(&err, "UpdateAndGet", database, collection, time.Now())
+	change := mgo.Change{Update: update, ReturnNew: true}
+	if _, err := c.apply(database, collection, selector, change, ModifyOptions{}, result); err != nil {
+		if err == ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// FindAndModifyInfo 按选项查找并修改数据,返回和Upsert一致的修改信息
+func (c *Client) FindAndModifyInfo(database, collection string, selector M, update M, opts ModifyOptions, result interface{}) (changed map[string]interface{}, err error) {
+	defer c.observe(&err, "FindAndModifyInfo", database, collection, time.Now())
+	change := mgo.Change{Update: update, Upsert: opts.Upsert, ReturnNew: opts.ReturnNew}
+	info, err := c.apply(database, collection, selector, change, opts, result)
+	if err != nil {
+		return nil, err
+	}
+	return changeInfoMap(info), nil
+}
+
+// FindAndRemoveInfo 查找并删除数据,返回和Upsert一致的修改信息
+func (c *Client) FindAndRemoveInfo(database, collection string, selector M, result interface{}) (changed map[string]interface{}, err error) {
+	defer c.observe(&err, "FindAndRemoveInfo", database, collection, time.Now())
+	info, err := c.apply(database, collection, selector, mgo.Change{Remove: true}, ModifyOptions{}, result)
+	if err != nil {
+		return nil, err
+	}
+	return changeInfoMap(info), nil
+}
+
+// apply 按选项执行findAndModify命令
+func (c *Client) apply(database, collection string, selector M, change mgo.Change, opts ModifyOptions, result interface{}) (*mgo.ChangeInfo, error) {
+	if err := c.ready(); err != nil {
+		return nil, err
+	}
+	if err := c.checkSelector(selector); err != nil {
+		return nil, err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	find := conn.Find(selector)
+	if len(opts.Sort) > 0 {
+		find.Sort(opts.Sort...)
+	}
+	if opts.Fields != nil {
+		find.Select(opts.Fields)
+	}
+	return c.applyChange(session, find, change, result)
+}
+
+// applyChange 执行findAndModify,结果先解码到局部的bson.Raw,成功后再解码到result,超时返回后result不会被写入
+func (c *Client) applyChange(session *mgo.Session, find *mgo.Query, change mgo.Change, result interface{}) (*mgo.ChangeInfo, error) {
+	var info *mgo.ChangeInfo
+	var raw bson.Raw
+	err := c.withWrite(session, func() error {
+		var err error
+		info, err = find.Apply(change, &raw)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result != nil && raw.Kind != 0 {
+		if err := raw.Unmarshal(result); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
+
+// changeInfoMap 将修改信息转换为map
+func changeInfoMap(info *mgo.ChangeInfo) map[string]interface{} {
+	return map[string]interface{}{"Matched": info.Matched, "Updated": info.Updated, "Removed": info.Removed, "UpsertedId": info.UpsertedId}
+}
+
 // FindAndRemove 查找并删除数据
-func (c *Client) FindAndRemove(database, collection string, selector M, result interface{}) (int, error) {
-	if c.connErr != nil {
-		return 0, c.connErr
+func (c *Client) FindAndRemove(database, collection string, selector M, result interface{}) (removed int, err error) {
+	defer c.observe(&err, "FindAndRemove", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return 0, err
+	}
+	if err := c.checkSelector(selector); err != nil {
+		return 0, err
 	}
 	session := c.session.Copy()
 	defer func() {
@@ -259,36 +1411,221 @@ func (c *Client) FindAndRemove(database, collection string, selector M, result i
 	}()
 	change := mgo.Change{Remove: true}
 	conn := session.DB(database).C(collection)
-	info, err := conn.Find(selector).Apply(change, result)
-	var removed int
+	info, err := c.applyChange(session, conn.Find(selector), change, result)
 	if err == nil {
 		removed = info.Removed
 	}
 	return removed, err
 }
 
+// NextSequence 将计数器集合中_id为name的seq字段原子加1并返回新值,不存在时从1开始,用于生成自增id
+// 计数器不存在时并发的upsert可能有一个报唯一索引冲突,此时计数器已被创建,重试一次即可
+func (c *Client) NextSequence(database, collection, name string) (seq int64, err error) {
+	defer c.observe(&err, "NextSequence", database, collection, time.Now())
+	var result struct {
+		Seq int64 `bson:"seq"`
+	}
+	change := mgo.Change{Update: M{"$inc": M{"seq": 1}}, Upsert: true, ReturnNew: true}
+	_, err = c.apply(database, collection, M{"_id": name}, change, ModifyOptions{}, &result)
+	if IsDup(err) {
+		_, err = c.apply(database, collection, M{"_id": name}, change, ModifyOptions{}, &result)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return result.Seq, nil
+}
+
 // GetPipeRow 使用管道进行聚合计算并返回一行数据
-func (c *Client) GetPipeRow(database, collection string, pipeline []M, result *M) error {
-	if c.connErr != nil {
-		return c.connErr
+func (c *Client) GetPipeRow(database, collection string, pipeline []M, result *M) (err error) {
+	defer c.observe(&err, "GetPipeRow", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return err
 	}
 	session := c.session.Copy()
 	defer func() {
 		session.Close()
 	}()
 	conn := session.DB(database).C(collection)
-	return conn.Pipe(pipeline).One(result)
+	return c.withRetry(session, func() error {
+		return c.pipe(conn, pipeline).One(result)
+	})
 }
 
 // GetPipeResult 使用管道进行聚合计算并返回多行结果集
-func (c *Client) GetPipeResult(database, collection string, pipeline []M, result *[]M) error {
-	if c.connErr != nil {
-		return c.connErr
+func (c *Client) GetPipeResult(database, collection string, pipeline []M, result *[]M) (err error) {
+	defer c.observe(&err, "GetPipeResult", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return err
 	}
 	session := c.session.Copy()
 	defer func() {
 		session.Close()
 	}()
 	conn := session.DB(database).C(collection)
-	return conn.Pipe(pipeline).All(result)
+	return c.withRetry(session, func() error {
+		return c.pipe(conn, pipeline).All(result)
+	})
+}
+
+// PipeRun 执行以$out或$merge结尾的聚合管道,只关心写入其他集合的副作用,不解码结果
+func (c *Client) PipeRun(database, collection string, pipeline []M) (err error) {
+	defer c.observe(&err, "PipeRun", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	return c.withRetry(session, func() error {
+		return c.pipe(conn, pipeline).Iter().Close()
+	})
+}
+
+// GetPipeInto 使用管道进行聚合计算并将结果集解码到任意切片指针,如*[]struct
+func (c *Client) GetPipeInto(database, collection string, pipeline []M, result interface{}) (err error) {
+	defer c.observe(&err, "GetPipeInto", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	return c.withRetry(session, func() error {
+		return c.pipe(conn, pipeline).All(result)
+	})
+}
+
+// PipeCount 返回聚合管道输出的数据条数,没有数据时返回0
+func (c *Client) PipeCount(database, collection string, pipeline []M) (count int, err error) {
+	defer c.observe(&err, "PipeCount", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return 0, err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	stages := make([]M, 0, len(pipeline)+1)
+	stages = append(stages, pipeline...)
+	stages = append(stages, M{"$count": "n"})
+	var result struct {
+		N int `bson:"n"`
+	}
+	err = c.withRetry(session, func() error {
+		return c.pipe(conn, stages).One(&result)
+	})
+	if err == ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return result.N, nil
+}
+
+// GroupCount 按field分组统计满足query的数据条数,返回值到条数的map
+// 非字符串的值通过fmt.Sprint转为字符串作为键,不同类型转换后相同时会合并,如1和"1",缺少field的数据键为""
+func (c *Client) GroupCount(database, collection string, field string, query M) (counts map[string]int, err error) {
+	defer c.observe(&err, "GroupCount", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return nil, err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	pipeline := []M{
+		{"$match": query},
+		{"$group": M{"_id": "$" + field, "count": M{"$sum": 1}}},
+	}
+	var result []struct {
+		ID    interface{} `bson:"_id"`
+		Count int         `bson:"count"`
+	}
+	err = c.withRetry(session, func() error {
+		return c.pipe(conn, pipeline).All(&result)
+	})
+	if err != nil {
+		return nil, err
+	}
+	counts = make(map[string]int, len(result))
+	for _, group := range result {
+		key := ""
+		if group.ID != nil {
+			key = fmt.Sprint(group.ID)
+		}
+		counts[key] += group.Count
+	}
+	return counts, nil
+}
+
+// FacetBucket 分组统计结果
+type FacetBucket struct {
+	Value interface{} `bson:"_id"`
+	Count int         `bson:"count"`
+}
+
+// FacetCount 按field分组统计满足query的数据条数,按条数从多到少返回前limit组,limit为0时返回所有分组
+func (c *Client) FacetCount(database, collection, field string, query M, limit int) (buckets []FacetBucket, err error) {
+	defer c.observe(&err, "FacetCount", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return nil, err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	pipeline := []M{
+		{"$match": query},
+		{"$group": M{"_id": "$" + field, "count": M{"$sum": 1}}},
+		{"$sort": bson.D{{Name: "count", Value: -1}, {Name: "_id", Value: 1}}},
+	}
+	if limit > 0 {
+		pipeline = append(pipeline, M{"$limit": limit})
+	}
+	err = c.withRetry(session, func() error {
+		return c.pipe(conn, pipeline).All(&buckets)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+// GetPipeResultOpt 按管道选项进行聚合计算并返回多行结果集
+func (c *Client) GetPipeResultOpt(database, collection string, pipeline []M, opts PipeOptions, result interface{}) (err error) {
+	defer c.observe(&err, "GetPipeResultOpt", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	pipe := c.pipe(conn, commentPipeline(pipeline, opts.Comment))
+	applyPipeOptions(pipe, opts)
+	return maxTimeError(c.withRetry(session, func() error {
+		return pipe.All(result)
+	}))
+}
+
+// applyPipeOptions 将管道选项应用到聚合管道上
+func applyPipeOptions(pipe *mgo.Pipe, opts PipeOptions) {
+	if opts.AllowDiskUse {
+		pipe.AllowDiskUse()
+	}
+	if opts.BatchSize > 0 {
+		pipe.Batch(opts.BatchSize)
+	}
+	if opts.MaxTimeMS > 0 {
+		pipe.SetMaxTime(time.Duration(opts.MaxTimeMS) * time.Millisecond)
+	}
+	if opts.Collation != nil {
+		pipe.Collation(opts.Collation)
+	}
+}
+
+// commentPipeline 在管道最前面插入带$comment的$match阶段,mgo的聚合命令不支持comment选项
+func commentPipeline(pipeline []M, comment string) []M {
+	if comment == "" {
+		return pipeline
+	}
+	return append([]M{{"$match": M{"$comment": comment}}}, pipeline...)
 }
@@ -0,0 +1,498 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+func TestClientNotReady(t *testing.T) {
+	closed := &Client{}
+	closed.Close()
+	closed.Close()
+	for _, tt := range []struct {
+		name string
+		c    *Client
+		want error
+	}{
+		{"zero", &Client{}, ErrNotConnected},
+		{"closed", closed, ErrClosed},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			c := tt.c
+			var row M
+			var rows []M
+			_, countErr := c.GetCount(testDB, "coll", nil)
+			for op, err := range map[string]error{
+				"Ping":      c.Ping(),
+				"GetRow":    c.GetRow(testDB, "coll", nil, nil, &row),
+				"GetResult": c.GetResult(testDB, "coll", nil, nil, nil, &rows),
+				"GetCount":  countErr,
+				"Insert":    c.Insert(testDB, "coll", M{"n": 1}),
+				"Update":    c.Update(testDB, "coll", M{"n": 1}, M{"$set": M{"n": 2}}),
+				"Remove":    c.Remove(testDB, "coll", M{"n": 1}),
+			} {
+				if !errors.Is(err, tt.want) {
+					t.Errorf("%s = %v, want %v", op, err, tt.want)
+				}
+			}
+			if s := c.Session(); s != nil {
+				t.Errorf("Session = %v, want nil", s)
+			}
+		})
+	}
+}
+
+func TestHintKeys(t *testing.T) {
+	tests := []struct {
+		hint string
+		want []string
+	}{
+		{"name", []string{"name"}},
+		{"-age", []string{"-age"}},
+		{"name,-age", []string{"name", "-age"}},
+		{"name, -age", []string{"name", "-age"}},
+		{"user_id,", []string{"user_id"}},
+		{"name_1", []string{"name"}},
+		{"name_1_age_-1", []string{"name", "-age"}},
+		{"user_id_1_created_at_-1", []string{"user_id", "-created_at"}},
+		{"my_index", nil},
+		{"email_unique", nil},
+		{"name_1_extra", nil},
+	}
+	for _, tt := range tests {
+		if got := hintKeys(tt.hint); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("hintKeys(%q) = %q, want %q", tt.hint, got, tt.want)
+		}
+	}
+	if !useFindCommand(FindOptions{Hint: "email_unique"}) || useFindCommand(FindOptions{Hint: "name_1"}) {
+		t.Error("only custom index names should use the find command")
+	}
+}
+
+// explainIndex 返回执行计划中使用的索引名称
+func explainIndex(v interface{}) string {
+	switch v := v.(type) {
+	case bson.M:
+		if name, ok := v["indexName"].(string); ok {
+			return name
+		}
+		for _, key := range []string{"queryPlanner", "winningPlan", "queryPlan", "inputStage"} {
+			if name := explainIndex(v[key]); name != "" {
+				return name
+			}
+		}
+	case []interface{}:
+		for _, elem := range v {
+			if name := explainIndex(elem); name != "" {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+func TestHintIndexName(t *testing.T) {
+	c := testClient(t)
+	coll := testCollection(t, c, "hint_name")
+	for _, index := range []Index{
+		{Key: []string{"email"}, Name: "email_unique", Unique: true},
+		{Key: []string{"email", "n"}, Name: "email_n"},
+	} {
+		if err := c.EnsureIndex(testDB, coll, index); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		if err := c.Insert(testDB, coll, M{"email": fmt.Sprintf("u%d@example.com", i), "n": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	query := M{"email": "u3@example.com"}
+	for _, hint := range []string{"email_unique", "email_n", "email_1_n_1"} {
+		var plan bson.M
+		if err := c.Explain(testDB, coll, query, FindOptions{Hint: hint}, &plan); err != nil {
+			t.Fatalf("Explain(%s): %v", hint, err)
+		}
+		want := hint
+		if hint == "email_1_n_1" {
+			want = "email_n"
+		}
+		if got := explainIndex(plan); got != want {
+			t.Fatalf("Explain(%s) used index %q, want %q", hint, got, want)
+		}
+	}
+	var row M
+	if err := c.GetRow(testDB, coll, query, M{"Hint": "email_unique"}, &row); err != nil || row["n"] != 3 {
+		t.Fatalf("GetRow = %v, %v", row, err)
+	}
+	var rows []M
+	if err := c.GetResultOpt(testDB, coll, M{}, nil, FindOptions{Hint: "email_n", Sort: Sort{"email"}}, &rows); err != nil || len(rows) != 5 {
+		t.Fatalf("GetResultOpt = %d rows, %v", len(rows), err)
+	}
+	if err := c.GetRow(testDB, coll, M{"email": "missing"}, M{"Hint": "email_unique"}, &row); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetRow missing = %v, want ErrNotFound", err)
+	}
+}
+
+func TestObjectIDTime(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 30, 45, 500, time.UTC)
+	id := ObjectIDFromTime(now)
+	if got := TimeFromObjectID(id); !got.Equal(now.Truncate(time.Second)) {
+		t.Fatalf("TimeFromObjectID = %v, want %v", got, now.Truncate(time.Second))
+	}
+	if next := NewObjectID(); next <= ObjectIDFromTime(time.Now().Add(-time.Minute)) {
+		t.Fatalf("NewObjectID %v sorts before an id from a minute ago", next)
+	}
+}
+
+func BenchmarkDeepPage(b *testing.B) {
+	const limit, page = 10, 10000
+	c := testClient(b)
+	coll := testSeed(b, c, "bench_page", limit*(page+1))
+	var last []M
+	if err := c.GetResultOpt(testDB, coll, nil, M{"_id": 1}, FindOptions{Sort: Sort{"_id"}, Skip: limit*page - 1, Limit: 1}, &last); err != nil || len(last) != 1 {
+		b.Fatalf("find page start: %v", err)
+	}
+	afterID := last[0]["_id"].(ObjectID)
+	b.Run("Skip", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var result []M
+			if err := c.GetResultOpt(testDB, coll, nil, nil, FindOptions{Sort: Sort{"_id"}, Skip: limit * page, Limit: limit}, &result); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("After", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var result []M
+			if _, err := c.GetResultAfter(testDB, coll, nil, afterID, limit, &result); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkSingleBatch(b *testing.B) {
+	const limit = 1000
+	c := testClient(b)
+	coll := testSeed(b, c, "bench_batch", limit)
+	b.Run("DefaultBatch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var result []M
+			if err := c.GetResultOpt(testDB, coll, nil, nil, FindOptions{Limit: limit}, &result); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("SingleBatch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var result []M
+			if err := c.GetResultSingleBatch(testDB, coll, nil, nil, limit, &result); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkPoolLimit(b *testing.B) {
+	coll := testSeed(b, testClient(b), "bench_pool", 100)
+	for _, limit := range []int{1, 4, 64} {
+		b.Run(fmt.Sprintf("Limit%d", limit), func(b *testing.B) {
+			c, err := ConnWithOptions(ConnOptions{Addrs: testAddrs(), Timeout: time.Second, PoolLimit: limit})
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer c.Close()
+			b.SetParallelism(16)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					var row M
+					if err := c.GetRow(testDB, coll, M{"n": 50}, nil, &row); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestCheckSelector(t *testing.T) {
+	c := &Client{}
+	if err := c.checkSelector(nil); err != nil {
+		t.Fatalf("checkSelector with safe deletes off = %v", err)
+	}
+	c.SetSafeDeletes(true)
+	for _, selector := range []M{nil, {}} {
+		if err := c.checkSelector(selector); err != ErrEmptySelector {
+			t.Fatalf("checkSelector(%v) = %v, want ErrEmptySelector", selector, err)
+		}
+	}
+	if err := c.checkSelector(M{"_id": 1}); err != nil {
+		t.Fatalf("checkSelector = %v, want nil", err)
+	}
+	if b := c.Bulk(testDB, "coll").Update(M{"a": 1}, Set(M{"b": 1}), M{}, Set(M{"b": 2})); b.err != ErrEmptySelector {
+		t.Fatalf("Bulk.Update err = %v, want ErrEmptySelector", b.err)
+	}
+	if b := c.Bulk(testDB, "coll").Remove(M{"a": 1}, nil); b.err != ErrEmptySelector {
+		t.Fatalf("Bulk.Remove err = %v, want ErrEmptySelector", b.err)
+	}
+}
+
+func TestSafeDeletes(t *testing.T) {
+	c := testClient(t)
+	coll := testSeed(t, c, "safe_deletes", 3)
+	c.SetSafeDeletes(true)
+	defer c.SetSafeDeletes(false)
+	_, updateAllErr := c.UpdateAll(testDB, coll, nil, Set(M{"x": 1}))
+	_, updateInfoErr := c.UpdateInfo(testDB, coll, M{}, Set(M{"x": 1}))
+	_, removeAllErr := c.RemoveAll(testDB, coll, nil)
+	_, bulkErr := c.Bulk(testDB, coll).Remove(nil).Run()
+	var doc M
+	_, findAndModifyErr := c.FindAndModify(testDB, coll, nil, Set(M{"x": 1}), false, &doc)
+	_, findAndModifyOptErr := c.FindAndModifyOpt(testDB, coll, M{}, Set(M{"x": 1}), ModifyOptions{}, &doc)
+	_, findAndModifyInfoErr := c.FindAndModifyInfo(testDB, coll, nil, Set(M{"x": 1}), ModifyOptions{}, &doc)
+	_, updateAndGetErr := c.UpdateAndGet(testDB, coll, nil, Set(M{"x": 1}), &doc)
+	_, findAndRemoveErr := c.FindAndRemove(testDB, coll, nil, &doc)
+	_, findAndRemoveInfoErr := c.FindAndRemoveInfo(testDB, coll, M{}, &doc)
+	for op, err := range map[string]error{
+		"Update":                 c.Update(testDB, coll, nil, Set(M{"x": 1})),
+		"UpdateContext":          c.UpdateContext(context.Background(), testDB, coll, M{}, Set(M{"x": 1})),
+		"UpdateAll":              updateAllErr,
+		"UpdateInfo":             updateInfoErr,
+		"UpdateWithArrayFilters": c.UpdateWithArrayFilters(testDB, coll, nil, Set(M{"x": 1}), nil),
+		"Remove":                 c.Remove(testDB, coll, nil),
+		"RemoveAll":              removeAllErr,
+		"Bulk":                   bulkErr,
+		"FindAndModify":          findAndModifyErr,
+		"FindAndModifyOpt":       findAndModifyOptErr,
+		"FindAndModifyInfo":      findAndModifyInfoErr,
+		"UpdateAndGet":           updateAndGetErr,
+		"FindAndRemove":          findAndRemoveErr,
+		"FindAndRemoveInfo":      findAndRemoveInfoErr,
+	} {
+		if !errors.Is(err, ErrEmptySelector) {
+			t.Errorf("%s = %v, want ErrEmptySelector", op, err)
+		}
+	}
+	if n, err := c.GetCount(testDB, coll, M{"x": 1}); err != nil || n != 0 {
+		t.Fatalf("updated %d docs, err %v", n, err)
+	}
+	c.SetSafeDeletes(false)
+	removed, err := c.RemoveAll(testDB, coll, nil)
+	if err != nil || removed != 3 {
+		t.Fatalf("RemoveAll with safe deletes off = %d, %v", removed, err)
+	}
+}
+
+func TestUpsertSetOnInsertEmpty(t *testing.T) {
+	c := &Client{}
+	if _, err := c.UpsertSetOnInsert(testDB, "coll", M{"_id": 1}, nil, M{}); err != ErrEmptyUpdate {
+		t.Fatalf("UpsertSetOnInsert = %v, want ErrEmptyUpdate", err)
+	}
+}
+
+func TestVersionedUpdate(t *testing.T) {
+	want := M{"$set": M{"a": 1}, "$inc": M{"n": 2, "v": 1}}
+	for _, inc := range []interface{}{
+		M{"n": 2},
+		map[string]interface{}{"n": 2},
+		bson.D{{Name: "n", Value: 2}},
+	} {
+		update := M{"$set": M{"a": 1}, "$inc": inc}
+		got, err := versionedUpdate(update, "v")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("versionedUpdate(%T) = %#v, want %#v", inc, got, want)
+		}
+		if !reflect.DeepEqual(update["$inc"], inc) {
+			t.Errorf("versionedUpdate modified $inc: %#v", update["$inc"])
+		}
+	}
+	got, err := versionedUpdate(M{"$set": M{"a": 1}}, "v")
+	if err != nil || !reflect.DeepEqual(got, M{"$set": M{"a": 1}, "$inc": M{"v": 1}}) {
+		t.Fatalf("versionedUpdate without $inc = %#v, %v", got, err)
+	}
+	if _, err := versionedUpdate(M{"$inc": "n"}, "v"); err == nil {
+		t.Fatal("versionedUpdate accepted a string $inc")
+	}
+}
+
+func TestUpdateWithArrayFilters(t *testing.T) {
+	c := testClient(t)
+	coll := testCollection(t, c, "array_filters")
+	id := NewObjectID()
+	if err := c.Insert(testDB, coll, M{"_id": id, "grades": []M{{"score": 50}, {"score": 80}}}); err != nil {
+		t.Fatal(err)
+	}
+	err := c.UpdateWithArrayFilters(testDB, coll, M{"_id": id}, M{"$inc": M{"grades.$[g].score": 5}}, []M{{"g.score": M{"$lt": 60}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.UpdateWithArrayFilters(testDB, coll, M{"_id": id}, Set(M{"checked": true}), nil); err != nil {
+		t.Fatalf("without arrayFilters: %v", err)
+	}
+	var doc struct {
+		Grades []struct {
+			Score int `bson:"score"`
+		} `bson:"grades"`
+		Checked bool `bson:"checked"`
+	}
+	if err := c.GetById(testDB, coll, id, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Grades) != 2 || doc.Grades[0].Score != 55 || doc.Grades[1].Score != 80 || !doc.Checked {
+		t.Fatalf("doc = %+v", doc)
+	}
+	err = c.UpdateWithArrayFilters(testDB, coll, M{"_id": NewObjectID()}, Set(M{"checked": true}), nil)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("missing doc: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestEnsureObjectID(t *testing.T) {
+	type user struct {
+		ID   ObjectID `bson:"_id,omitempty"`
+		Name string   `bson:"name"`
+	}
+	type named struct {
+		ID string `bson:"_id"`
+	}
+	ptr := &user{Name: "a"}
+	id, err := ensureObjectID(ptr)
+	if err != nil || !id.Valid() || ptr.ID != id {
+		t.Fatalf("pointer struct: id %q, field %q, err %v", id, ptr.ID, err)
+	}
+	if _, err := ensureObjectID(user{Name: "b"}); err == nil {
+		t.Fatal("non-pointer struct without _id: want an error")
+	}
+	existing := NewObjectID()
+	if id, err := ensureObjectID(user{ID: existing}); err != nil || id != existing {
+		t.Fatalf("non-pointer struct with _id: %q, %v", id, err)
+	}
+	if id, err := ensureObjectID(&named{ID: "custom"}); err != nil || id != "" {
+		t.Fatalf("string _id: %q, %v, want empty id", id, err)
+	}
+	if id, err := ensureObjectID(M{"_id": 7}); err != nil || id != "" {
+		t.Fatalf("int _id: %q, %v, want empty id", id, err)
+	}
+	doc := M{"name": "c"}
+	if id, err := ensureObjectID(doc); err != nil || doc["_id"] != id {
+		t.Fatalf("M without _id: %q, %v, doc %v", id, err, doc)
+	}
+}
+
+func TestInsertReturnIDs(t *testing.T) {
+	c := testClient(t)
+	coll := testCollection(t, c, "insert_return_ids")
+	ids, err := c.InsertReturnIDs(testDB, coll, M{"n": 1}, M{"n": 2}, M{"n": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 3 {
+		t.Fatalf("got %d ids, want 3", len(ids))
+	}
+	seen := map[ObjectID]bool{}
+	for i, id := range ids {
+		if !id.Valid() || seen[id] {
+			t.Fatalf("ids = %v", ids)
+		}
+		seen[id] = true
+		var doc M
+		if err := c.GetById(testDB, coll, id, &doc); err != nil || doc["n"] != i+1 {
+			t.Fatalf("GetById(%s) = %v, %v", id.Hex(), doc, err)
+		}
+	}
+}
+
+func TestNextSequence(t *testing.T) {
+	c := testClient(t)
+	coll := testCollection(t, c, "sequences")
+	for want := int64(1); want <= 20; want++ {
+		seq, err := c.NextSequence(testDB, coll, "orders")
+		if err != nil || seq != want {
+			t.Fatalf("NextSequence = %d, %v, want %d", seq, err, want)
+		}
+	}
+
+	//计数器不存在时并发获取,所有值连续且不重复
+	const n = 50
+	seqs := make([]int64, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			seqs[i], errs[i] = c.NextSequence(testDB, coll, "invoices")
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	for i, seq := range seqs {
+		if seq != int64(i+1) {
+			t.Fatalf("sequences = %v, want 1..%d without gaps", seqs, n)
+		}
+	}
+}
+
+func TestUpdateAndGet(t *testing.T) {
+	c := testClient(t)
+	coll := testCollection(t, c, "update_and_get")
+	id := NewObjectID()
+	if err := c.Insert(testDB, coll, M{"_id": id, "n": 1, "name": "a"}); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		N    int    `bson:"n"`
+		Name string `bson:"name"`
+	}
+	found, err := c.UpdateAndGet(testDB, coll, M{"_id": id}, M{"$inc": M{"n": 1}, "$set": M{"name": "b"}}, &doc)
+	if err != nil || !found {
+		t.Fatalf("UpdateAndGet = %v, %v", found, err)
+	}
+	if doc.N != 2 || doc.Name != "b" {
+		t.Fatalf("doc = %+v, want the updated document", doc)
+	}
+
+	doc.N, doc.Name = 0, ""
+	found, err = c.UpdateAndGet(testDB, coll, M{"_id": NewObjectID()}, Set(M{"name": "c"}), &doc)
+	if err != nil || found {
+		t.Fatalf("missing doc: UpdateAndGet = %v, %v, want false and nil", found, err)
+	}
+	if doc.N != 0 || doc.Name != "" {
+		t.Fatalf("result written for a missing doc: %+v", doc)
+	}
+	if n, err := c.GetCount(testDB, coll, M{}); err != nil || n != 1 {
+		t.Fatalf("count = %d, %v, want 1", n, err)
+	}
+}
+
+func TestGetResultSingleBatchLimit(t *testing.T) {
+	c := &Client{}
+	var result []M
+	for _, limit := range []int{0, -1} {
+		if err := c.GetResultSingleBatch("db", "users", nil, nil, limit, &result); err != ErrInvalidLimit {
+			t.Fatalf("limit %d: err = %v, want ErrInvalidLimit", limit, err)
+		}
+	}
+	if err := c.GetResultSingleBatch("db", "users", nil, nil, 1, &result); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("limit 1: err = %v, want ErrNotConnected", err)
+	}
+}
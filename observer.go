@@ -0,0 +1,33 @@
+package mongo
+
+import "time"
+
+// QueryEvent 操作完成事件,用于统计耗时和错误
+type QueryEvent struct {
+	Op         string
+	Database   string
+	Collection string
+	Duration   time.Duration
+	Err        error
+}
+
+// SetObserver 设置操作完成回调,每次操作结束时调用一次,可用于接入监控或日志,传nil取消
+// fn在操作所在的goroutine中同步调用,应尽快返回
+func (c *Client) SetObserver(fn func(ev QueryEvent)) {
+	c.observer = fn
+}
+
+// observe 包装错误并通知observer,需在操作开始时defer调用
+func (c *Client) observe(err *error, op, database, collection string, start time.Time) {
+	wrapError(err, op, database, collection)
+	if c.observer == nil {
+		return
+	}
+	c.observer(QueryEvent{
+		Op:         op,
+		Database:   database,
+		Collection: collection,
+		Duration:   time.Since(start),
+		Err:        *err,
+	})
+}
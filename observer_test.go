@@ -0,0 +1,51 @@
+package mongo
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestObserver(t *testing.T) {
+	var events []QueryEvent
+	record := func(ev QueryEvent) { events = append(events, ev) }
+
+	//未连接时同样通知observer
+	nc := &Client{}
+	nc.SetObserver(record)
+	var row M
+	if err := nc.GetRow("db", "users", M{}, nil, &row); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("GetRow = %v, want ErrNotConnected", err)
+	}
+	if len(events) != 1 || events[0].Op != "GetRow" || events[0].Database != "db" || events[0].Collection != "users" || !errors.Is(events[0].Err, ErrNotConnected) {
+		t.Fatalf("events = %+v", events)
+	}
+	nc.SetObserver(nil)
+	if err := nc.Insert("db", "users", M{}); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("Insert without observer = %v", err)
+	}
+
+	c := testClient(t)
+	coll := testCollection(t, c, "observer")
+	events = nil
+	c.SetObserver(record)
+	defer c.SetObserver(nil)
+	if err := c.Insert(testDB, coll, M{"n": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.GetRow(testDB, coll, M{"n": 1}, nil, &row); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2: %+v", len(events), events)
+	}
+	for i, op := range []string{"Insert", "GetRow"} {
+		ev := events[i]
+		if ev.Op != op || ev.Database != testDB || ev.Collection != coll || ev.Err != nil || ev.Duration <= 0 {
+			t.Fatalf("event %d = %+v, want a successful %s", i, ev, op)
+		}
+	}
+	c.SetObserver(nil)
+	if err := c.GetRow(testDB, coll, M{"n": 1}, nil, &row); err != nil {
+		t.Fatalf("GetRow with nil observer: %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package mongo
+
+// Pipeline 聚合管道构建器,可直接作为[]M传给GetPipeResult等方法
+// 如Pipeline{}.Match(M{"status": 1}).Group(M{"_id": "$type", "n": M{"$sum": 1}})
+type Pipeline []M
+
+// stage 返回追加了阶段的新管道,不修改原管道
+func (p Pipeline) stage(stage M) Pipeline {
+	return append(p[:len(p):len(p)], stage)
+}
+
+// Match 追加$match阶段
+func (p Pipeline) Match(query M) Pipeline {
+	return p.stage(M{"$match": query})
+}
+
+// Group 追加$group阶段
+func (p Pipeline) Group(group M) Pipeline {
+	return p.stage(M{"$group": group})
+}
+
+// Sort 追加$sort阶段,M是无序的,多字段排序时需要直接追加bson.D形式的$sort阶段
+func (p Pipeline) Sort(sort M) Pipeline {
+	return p.stage(M{"$sort": sort})
+}
+
+// Project 追加$project阶段
+func (p Pipeline) Project(project M) Pipeline {
+	return p.stage(M{"$project": project})
+}
+
+// Limit 追加$limit阶段
+func (p Pipeline) Limit(n int) Pipeline {
+	return p.stage(M{"$limit": n})
+}
+
+// Skip 追加$skip阶段
+func (p Pipeline) Skip(n int) Pipeline {
+	return p.stage(M{"$skip": n})
+}
+
+// Lookup 追加$lookup阶段,关联from集合中foreignField等于localField的数据到as字段
+func (p Pipeline) Lookup(from, localField, foreignField, as string) Pipeline {
+	return p.stage(M{"$lookup": M{"from": from, "localField": localField, "foreignField": foreignField, "as": as}})
+}
+
+// Unwind 追加$unwind阶段,path需要以$开头,如"$items"
+func (p Pipeline) Unwind(path string) Pipeline {
+	return p.stage(M{"$unwind": path})
+}
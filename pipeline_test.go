@@ -0,0 +1,43 @@
+package mongo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPipeline(t *testing.T) {
+	p := Pipeline{}.
+		Match(M{"status": 1}).
+		Lookup("users", "uid", "_id", "user").
+		Unwind("$user").
+		Group(M{"_id": "$type", "n": M{"$sum": 1}}).
+		Sort(M{"n": -1}).
+		Skip(10).
+		Limit(5).
+		Project(M{"n": 1})
+	want := Pipeline{
+		{"$match": M{"status": 1}},
+		{"$lookup": M{"from": "users", "localField": "uid", "foreignField": "_id", "as": "user"}},
+		{"$unwind": "$user"},
+		{"$group": M{"_id": "$type", "n": M{"$sum": 1}}},
+		{"$sort": M{"n": -1}},
+		{"$skip": 10},
+		{"$limit": 5},
+		{"$project": M{"n": 1}},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Fatalf("pipeline = %#v, want %#v", p, want)
+	}
+}
+
+func TestPipelineBranches(t *testing.T) {
+	base := make(Pipeline, 0, 4).Match(M{"a": 1})
+	first := base.Limit(1)
+	second := base.Skip(1)
+	if len(base) != 1 {
+		t.Fatalf("base modified: %#v", base)
+	}
+	if !reflect.DeepEqual(first[1], M{"$limit": 1}) || !reflect.DeepEqual(second[1], M{"$skip": 1}) {
+		t.Fatalf("branches share stages: %#v %#v", first, second)
+	}
+}
@@ -0,0 +1,117 @@
+package mongo
+
+import (
+	"regexp"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+// Gt 返回大于条件
+func Gt(v interface{}) M {
+	return M{"$gt": v}
+}
+
+// Gte 返回大于等于条件
+func Gte(v interface{}) M {
+	return M{"$gte": v}
+}
+
+// Lt 返回小于条件
+func Lt(v interface{}) M {
+	return M{"$lt": v}
+}
+
+// Lte 返回小于等于条件
+func Lte(v interface{}) M {
+	return M{"$lte": v}
+}
+
+// Ne 返回不等于条件
+func Ne(v interface{}) M {
+	return M{"$ne": v}
+}
+
+// In 返回在列表中的条件
+func In(values ...interface{}) M {
+	return M{"$in": values}
+}
+
+// Nin 返回不在列表中的条件
+func Nin(values ...interface{}) M {
+	return M{"$nin": values}
+}
+
+// Between 返回在lo和hi之间的条件,包含lo和hi,如M{"age": Between(18, 65)}
+func Between(lo, hi interface{}) M {
+	return M{"$gte": lo, "$lte": hi}
+}
+
+// Regex 返回正则匹配条件,options如"i"表示忽略大小写,如M{"name": Regex("^foo", "i")}
+func Regex(pattern, options string) interface{} {
+	return bson.RegEx{Pattern: pattern, Options: options}
+}
+
+// Prefix 返回忽略大小写的前缀匹配条件,s中的正则特殊字符会被转义
+func Prefix(s string) interface{} {
+	return Regex("^"+regexp.QuoteMeta(s), "i")
+}
+
+// ElemMatch 返回数组元素匹配条件,如M{"items": ElemMatch(M{"qty": Gt(5)})}
+// 也可用于fields参数只返回第一个匹配的数组元素,如fields为M{"items": ElemMatch(M{"qty": Gt(5)})}
+func ElemMatch(cond M) M {
+	return M{"$elemMatch": cond}
+}
+
+// Slice 返回数组切片投影,n为正数时返回前n个元素,为负数时返回最后-n个元素
+// 用于fields参数,如M{"comments": Slice(-3)}
+func Slice(n int) interface{} {
+	return M{"$slice": n}
+}
+
+// SliceRange 返回跳过skip个元素后取limit个元素的数组切片投影,skip为负数时从末尾计算
+func SliceRange(skip, limit int) interface{} {
+	return M{"$slice": []int{skip, limit}}
+}
+
+// And 返回同时满足所有查询的条件
+func And(queries ...M) M {
+	return M{"$and": queries}
+}
+
+// Or 返回满足任一查询的条件
+func Or(queries ...M) M {
+	return M{"$or": queries}
+}
+
+// ParseQuery 将MongoDB扩展JSON字符串解析为查询条件,嵌套的对象(包括数组中的对象)也转换为M
+// 支持{"$oid": "..."}转换为ObjectID,{"$date": "..."}转换为time.Time,数字与encoding/json一样解析为float64
+func ParseQuery(jsonStr string) (M, error) {
+	query := M{}
+	if err := bson.UnmarshalJSON([]byte(jsonStr), &query); err != nil {
+		return nil, err
+	}
+	return normalizeQuery(query).(M), nil
+}
+
+// normalizeQuery 将解析出的map[string]interface{}递归转换为M,便于调用方做类型断言
+func normalizeQuery(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(M, len(v))
+		for key, value := range v {
+			m[key] = normalizeQuery(value)
+		}
+		return m
+	case M:
+		for key, value := range v {
+			v[key] = normalizeQuery(value)
+		}
+		return v
+	case []interface{}:
+		for i, value := range v {
+			v[i] = normalizeQuery(value)
+		}
+		return v
+	}
+	return v
+}
@@ -0,0 +1,68 @@
+package mongo
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+)
+
+func TestQueryBuilders(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		got  interface{}
+		want interface{}
+	}{
+		{"Gt", Gt(1), M{"$gt": 1}},
+		{"Gte", Gte(1), M{"$gte": 1}},
+		{"Lt", Lt(1), M{"$lt": 1}},
+		{"Lte", Lte(1), M{"$lte": 1}},
+		{"Ne", Ne("a"), M{"$ne": "a"}},
+		{"In", In(1, "a"), M{"$in": []interface{}{1, "a"}}},
+		{"Nin", Nin(1, 2), M{"$nin": []interface{}{1, 2}}},
+		{"Between", Between(18, 65), M{"$gte": 18, "$lte": 65}},
+		{"And", And(M{"a": 1}, M{"b": 2}), M{"$and": []M{{"a": 1}, {"b": 2}}}},
+		{"Or", Or(M{"a": 1}, M{"b": 2}), M{"$or": []M{{"a": 1}, {"b": 2}}}},
+		{"Regex", Regex("^foo", "i"), bson.RegEx{Pattern: "^foo", Options: "i"}},
+		{"Prefix", Prefix("a.b(c"), bson.RegEx{Pattern: `^a\.b\(c`, Options: "i"}},
+		{"ElemMatch", ElemMatch(M{"qty": Gt(5)}), M{"$elemMatch": M{"qty": M{"$gt": 5}}}},
+		{"Slice", Slice(-3), M{"$slice": -3}},
+		{"SliceRange", SliceRange(10, 5), M{"$slice": []int{10, 5}}},
+	} {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s = %#v, want %#v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	id := bson.ObjectIdHex("5f1b2c3d4e5f6a7b8c9d0e1f")
+	q, err := ParseQuery(`{
+		"_id": {"$oid": "5f1b2c3d4e5f6a7b8c9d0e1f"},
+		"created": {"$gte": {"$date": "2020-01-02T03:04:05Z"}},
+		"age": {"$gt": 18, "$lte": 65},
+		"$or": [{"tags": {"$in": ["a", "b"]}}, {"owner": {"$oid": "5f1b2c3d4e5f6a7b8c9d0e1f"}}]
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := M{
+		"_id":     id,
+		"created": M{"$gte": time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+		"age":     M{"$gt": 18.0, "$lte": 65.0},
+		"$or":     []interface{}{M{"tags": M{"$in": []interface{}{"a", "b"}}}, M{"owner": id}},
+	}
+	if !reflect.DeepEqual(q, want) {
+		t.Fatalf("ParseQuery = %#v, want %#v", q, want)
+	}
+	if _, ok := q["age"].(M)["$gt"]; !ok {
+		t.Fatal("nested operator is not an M")
+	}
+}
+
+func TestParseQueryInvalid(t *testing.T) {
+	if _, err := ParseQuery(`{"a":`); err == nil {
+		t.Fatal("ParseQuery accepted invalid JSON")
+	}
+}
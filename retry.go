@@ -0,0 +1,128 @@
+package mongo
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/globalsign/mgo"
+)
+
+// SetRetry 设置遇到临时网络错误时的重试次数和重试间隔,n为0时不重试
+// 重试间隔按次数线性递增,非临时错误(如唯一索引冲突)不会重试
+// 默认只重试读操作和幂等的管理命令,写操作需要通过SetRetryWrites开启
+func (c *Client) SetRetry(n int, backoff time.Duration) {
+	c.retries = n
+	c.backoff = backoff
+}
+
+// SetRetryWrites 设置写操作(插入、更新、删除、FindAndModify、Bulk等)是否按SetRetry的设置重试,默认不重试
+// 连接在请求发出后断开时,第一次写入可能已经在服务端完成,重试会导致重复插入或$inc重复累加
+// 只应在写操作幂等时开启,如按_id的$set更新或带唯一索引的插入
+func (c *Client) SetRetryWrites(on bool) {
+	c.retryWrites = on
+}
+
+// withRetry 执行读操作fn,遇到临时网络错误时刷新session后重试
+// 设置了SetOperationTimeout时查询由服务端按maxTimeMS终止(见find和pipe),超过截止时间后不再重试
+func (c *Client) withRetry(session *mgo.Session, fn func() error) error {
+	start := time.Now()
+	return c.timeoutError(c.retry(session, fn, start), start)
+}
+
+// withWrite 执行写操作fn,只有通过SetRetryWrites开启后才会重试
+// 写命令不支持maxTimeMS,设置了SetOperationTimeout时通过bounded在客户端限制时间
+func (c *Client) withWrite(session *mgo.Session, fn func() error) error {
+	start := time.Now()
+	return c.bounded(session, func() error {
+		if !c.retryWrites {
+			return c.timeoutError(fn(), start)
+		}
+		return c.timeoutError(c.retry(session, fn, start), start)
+	})
+}
+
+// bounded 设置了SetOperationTimeout时在goroutine中执行fn,超时后关闭session并返回ErrOperationTimeout
+// mgo无法中断已发出的请求,超时后fn仍可能继续执行,fn只能写入出错时不再读取的变量,查询结果需先解码到局部变量
+func (c *Client) bounded(session *mgo.Session, fn func() error) error {
+	if c.opTimeout <= 0 {
+		return fn()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), c.opTimeout)
+	defer cancel()
+	err := runContext(ctx, session, nil, func(interface{}) error {
+		return fn()
+	})
+	if err == context.DeadlineExceeded {
+		return ErrOperationTimeout
+	}
+	return err
+}
+
+// retry 执行fn,遇到临时网络错误时刷新session后重试,设置了SetOperationTimeout时超过截止时间后不再重试
+func (c *Client) retry(session *mgo.Session, fn func() error, start time.Time) error {
+	err := fn()
+	for i := 0; i < c.retries && isTransient(err); i++ {
+		wait := c.backoff * time.Duration(i+1)
+		if c.opTimeout > 0 && time.Since(start)+wait >= c.opTimeout {
+			break
+		}
+		time.Sleep(wait)
+		session.Refresh()
+		err = fn()
+	}
+	return err
+}
+
+// timeoutError 设置了SetOperationTimeout且已超过截止时间时,将服务端的maxTimeMS错误转换为ErrOperationTimeout
+func (c *Client) timeoutError(err error, start time.Time) error {
+	if c.opTimeout > 0 && time.Since(start) >= c.opTimeout && maxTimeError(err) == ErrMaxTimeExceeded {
+		return ErrOperationTimeout
+	}
+	return err
+}
+
+// isTransient 返回错误是否为可重试的临时网络错误
+func isTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if err == io.EOF || err == mgo.ErrCursor {
+		return true
+	}
+	msg := err.Error()
+	for _, s := range []string{"no reachable servers", "connection reset", "broken pipe", "Closed explicitly"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTransient 返回错误是否为可重试的临时错误,包括网络错误和副本集切换主节点时的错误
+func IsTransient(err error) bool {
+	err = errorCause(err)
+	return isTransient(err) || IsNotMaster(err)
+}
+
+// IsNotMaster 返回错误是否为副本集选举期间的"not master"错误,选出新的主节点后可以重试
+func IsNotMaster(err error) bool {
+	err = errorCause(err)
+	if err == nil {
+		return false
+	}
+	var code int
+	switch e := err.(type) {
+	case *mgo.QueryError:
+		code = e.Code
+	case *mgo.LastError:
+		code = e.Code
+	}
+	switch code {
+	case 10107, 13435, 13436:
+		return true
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "not master") || strings.Contains(msg, "not primary")
+}
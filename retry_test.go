@@ -0,0 +1,170 @@
+package mongo
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/globalsign/mgo"
+)
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"eof", io.EOF, true},
+		{"cursor", mgo.ErrCursor, true},
+		{"no servers", errors.New("no reachable servers"), true},
+		{"reset", errors.New("read tcp: connection reset by peer"), true},
+		{"wrapped eof", Error{Op: "GetRow", Err: io.EOF}, true},
+		{"not master", &mgo.QueryError{Code: 10107}, true},
+		{"wrapped not master", Error{Op: "Insert", Err: &mgo.LastError{Err: "not master"}}, true},
+		{"dup", &mgo.LastError{Code: 11000}, false},
+		{"not found", ErrNotFound, false},
+	}
+	for _, tt := range tests {
+		if got := IsTransient(tt.err); got != tt.want {
+			t.Errorf("%s: IsTransient = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsNotMaster(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"query code", &mgo.QueryError{Code: 10107}, true},
+		{"last error code", &mgo.LastError{Code: 13435}, true},
+		{"recovering", &mgo.QueryError{Code: 13436}, true},
+		{"message", errors.New("not master and slaveOk=false"), true},
+		{"not primary", errors.New("not primary"), true},
+		{"wrapped", Error{Op: "Update", Err: &mgo.LastError{Code: 10107}}, true},
+		{"eof", io.EOF, false},
+		{"other code", &mgo.QueryError{Code: 2, Message: "bad value"}, false},
+	}
+	for _, tt := range tests {
+		if got := IsNotMaster(tt.err); got != tt.want {
+			t.Errorf("%s: IsNotMaster = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOperationTimeout(t *testing.T) {
+	c := testClient(t)
+	coll := testCollection(t, c, "operation_timeout")
+	if err := c.Insert(testDB, coll, M{"n": 1}, M{"n": 2}); err != nil {
+		t.Fatal(err)
+	}
+	c.SetOperationTimeout(50 * time.Millisecond)
+	defer c.SetOperationTimeout(0)
+	slow := M{"$where": "sleep(500) || true"}
+	var rows []M
+	var row M
+	var values []int
+	for name, op := range map[string]func() error{
+		"GetResult":    func() error { return c.GetResult(testDB, coll, slow, nil, nil, &rows) },
+		"GetResultOpt": func() error { return c.GetResultOpt(testDB, coll, slow, nil, FindOptions{}, &rows) },
+		"GetRow":       func() error { return c.GetRow(testDB, coll, slow, nil, &row) },
+		"GetCount": func() error {
+			_, err := c.GetCount(testDB, coll, slow)
+			return err
+		},
+		"Distinct": func() error { return c.Distinct(testDB, coll, "n", slow, &values) },
+		"GetPipeResult": func() error {
+			return c.GetPipeResult(testDB, coll, []M{{"$match": slow}}, &rows)
+		},
+		"UpdateAll": func() error {
+			_, err := c.UpdateAll(testDB, coll, slow, Set(M{"x": 1}))
+			return err
+		},
+		"RemoveAll": func() error {
+			_, err := c.RemoveAll(testDB, coll, slow)
+			return err
+		},
+		"FindAndModify": func() error {
+			_, err := c.FindAndModify(testDB, coll, slow, Set(M{"x": 1}), false, &row)
+			return err
+		},
+	} {
+		start := time.Now()
+		err := op()
+		if !errors.Is(err, ErrOperationTimeout) {
+			t.Errorf("%s = %v, want ErrOperationTimeout", name, err)
+		}
+		if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+			t.Errorf("%s took %v", name, elapsed)
+		}
+	}
+}
+
+func TestTimeoutError(t *testing.T) {
+	maxTime := &mgo.QueryError{Code: 50, Message: "operation exceeded time limit"}
+	c := &Client{opTimeout: 10 * time.Millisecond}
+	if err := c.timeoutError(maxTime, time.Now().Add(-time.Second)); err != ErrOperationTimeout {
+		t.Fatalf("after deadline: %v, want ErrOperationTimeout", err)
+	}
+	//用户设置的MaxTimeMS在操作超时之前触发时保持原错误
+	if err := c.timeoutError(maxTime, time.Now()); err != maxTime {
+		t.Fatalf("before deadline: %v, want the server error", err)
+	}
+	if err := (&Client{}).timeoutError(maxTime, time.Now().Add(-time.Second)); err != maxTime {
+		t.Fatalf("without timeout: %v, want the server error", err)
+	}
+}
+
+func TestRetryStopsAtDeadline(t *testing.T) {
+	c := &Client{retries: 3, backoff: 50 * time.Millisecond, opTimeout: 20 * time.Millisecond}
+	calls := 0
+	err := c.retry(nil, func() error {
+		calls++
+		return io.EOF
+	}, time.Now())
+	if err != io.EOF || calls != 1 {
+		t.Fatalf("retry = %v after %d calls, want io.EOF after 1", err, calls)
+	}
+}
+
+func TestWriteRetryOptIn(t *testing.T) {
+	c := &Client{retries: 3, backoff: time.Millisecond}
+	calls := 0
+	err := c.withWrite(nil, func() error {
+		calls++
+		return io.EOF
+	})
+	if err != io.EOF || calls != 1 {
+		t.Fatalf("withWrite = %v after %d calls, want io.EOF after 1", err, calls)
+	}
+}
+
+func TestRetryDroppedConnection(t *testing.T) {
+	coll := testSeed(t, testClient(t), "retry_dropped", 3)
+	proxy, c := testProxyClient(t)
+	c.SetRetry(2, 10*time.Millisecond)
+	if _, err := c.GetCount(testDB, coll, M{}); err != nil {
+		t.Fatal(err)
+	}
+
+	proxy.drop()
+	n, err := c.GetCount(testDB, coll, M{})
+	if err != nil || n != 3 {
+		t.Fatalf("GetCount after drop = %d, %v, want 3", n, err)
+	}
+
+	//写操作默认不重试,连接断开的错误直接返回
+	proxy.drop()
+	if err := c.Insert(testDB, coll, M{"n": 3}); !IsTransient(err) {
+		t.Fatalf("Insert after drop = %v, want a transient error", err)
+	}
+	c.SetRetryWrites(true)
+	proxy.drop()
+	if err := c.Insert(testDB, coll, M{"n": 4}); err != nil {
+		t.Fatalf("Insert with SetRetryWrites after drop: %v", err)
+	}
+}
@@ -0,0 +1,44 @@
+package mongo
+
+import "time"
+
+// TextSearch 使用文本索引进行全文搜索,需要先创建文本索引,如Index{Key: []string{"$text:title"}}
+// 结果的score字段为匹配得分,未指定opts.Sort时按得分从高到低排序
+func (c *Client) TextSearch(database, collection string, search string, opts FindOptions, result interface{}) (err error) {
+	defer c.observe(&err, "TextSearch", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	find := c.find(conn, M{"$text": M{"$search": search}}).Select(M{"score": M{"$meta": "textScore"}})
+	if len(opts.Sort) == 0 {
+		opts.Sort = Sort{"$textScore:score"}
+	}
+	applyFindOptions(find, opts)
+	return maxTimeError(c.withRetry(session, func() error {
+		return find.All(result)
+	}))
+}
+
+// GeoNear 查询距离指定经纬度maxMeters米以内的数据,按距离从近到远返回
+// field字段需要先创建2dsphere索引,如Index{Key: []string{"$2dsphere:location"}},maxMeters为0时不限制距离
+func (c *Client) GeoNear(database, collection string, field string, lng, lat, maxMeters float64, opts FindOptions, result interface{}) (err error) {
+	defer c.observe(&err, "GeoNear", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	near := M{"$geometry": M{"type": "Point", "coordinates": []float64{lng, lat}}}
+	if maxMeters > 0 {
+		near["$maxDistance"] = maxMeters
+	}
+	find := c.find(conn, M{field: M{"$near": near}})
+	applyFindOptions(find, opts)
+	return maxTimeError(c.withRetry(session, func() error {
+		return find.All(result)
+	}))
+}
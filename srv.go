@@ -0,0 +1,95 @@
+package mongo
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// srvScheme DNS SRV连接地址前缀
+const srvScheme = "mongodb+srv://"
+
+// DNS查询函数,测试时替换
+var (
+	lookupSRV = net.LookupSRV
+	lookupTXT = net.LookupTXT
+)
+
+// srvTXTOptions TXT记录中允许出现的连接参数
+var srvTXTOptions = map[string]bool{"authSource": true, "replicaSet": true}
+
+// srvIgnoredOptions mgo不支持的连接参数,解析时去掉,如Atlas地址中的retryWrites
+// mgo不会自动重试,需要重试时使用SetRetry
+var srvIgnoredOptions = map[string]bool{"retryWrites": true, "retryReads": true}
+
+// resolveSRV 将mongodb+srv://地址解析为mgo支持的mongodb://地址
+// 通过SRV记录获取节点地址,节点必须与主机名属于同一父域名,TXT记录中只允许authSource、replicaSet,并默认开启TLS
+func resolveSRV(urlAddr string) (string, error) {
+	u, err := url.Parse(urlAddr)
+	if err != nil {
+		return "", fmt.Errorf("parse srv url error: %s", err.Error())
+	}
+	host := u.Hostname()
+	if u.Port() != "" || strings.Contains(host, ",") {
+		return "", fmt.Errorf("srv url must have a single host without port: %s", host)
+	}
+	if strings.Count(host, ".") < 2 {
+		return "", fmt.Errorf("srv url host must have at least three parts: %s", host)
+	}
+	parent := host[strings.Index(host, "."):]
+	_, records, err := lookupSRV("mongodb", "tcp", host)
+	if err != nil {
+		return "", fmt.Errorf("lookup srv error: %s", err.Error())
+	}
+	addrs := make([]string, len(records))
+	for i, record := range records {
+		target := strings.TrimSuffix(record.Target, ".")
+		if !strings.HasSuffix(target, parent) {
+			return "", fmt.Errorf("srv record %s is not in the domain %s", target, parent[1:])
+		}
+		addrs[i] = fmt.Sprintf("%s:%d", target, record.Port)
+	}
+	options := []string{"ssl=true"}
+	txts, err := lookupTXT(host)
+	if err != nil {
+		if dnsErr, ok := err.(*net.DNSError); !ok || !dnsErr.IsNotFound {
+			return "", fmt.Errorf("lookup txt error: %s", err.Error())
+		}
+	}
+	if len(txts) > 1 {
+		return "", fmt.Errorf("srv url host has %d txt records, want at most 1", len(txts))
+	}
+	for _, txt := range txts {
+		for _, opt := range strings.Split(txt, "&") {
+			if !srvTXTOptions[strings.SplitN(opt, "=", 2)[0]] {
+				return "", fmt.Errorf("unsupported txt record option: %s", opt)
+			}
+			options = append(options, opt)
+		}
+	}
+	if u.RawQuery != "" {
+		for _, opt := range strings.Split(u.RawQuery, "&") {
+			if !srvIgnoredOptions[strings.SplitN(opt, "=", 2)[0]] {
+				options = append(options, opt)
+			}
+		}
+	}
+	var user string
+	if u.User != nil {
+		user = u.User.String() + "@"
+	}
+	return "mongodb://" + user + strings.Join(addrs, ",") + u.Path + "?" + strings.Join(options, "&"), nil
+}
+
+// srvHost 返回mongodb+srv://地址中的主机名,不包含用户名和密码
+func srvHost(urlAddr string) string {
+	host := strings.TrimPrefix(urlAddr, srvScheme)
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		host = host[i+1:]
+	}
+	if i := strings.IndexAny(host, "/?"); i >= 0 {
+		host = host[:i]
+	}
+	return host
+}
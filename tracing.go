@@ -0,0 +1,50 @@
+package mongo
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName OpenTelemetry tracer名称
+const tracerName = "github.com/shideqin/mongo"
+
+// SetTracerProvider 设置OpenTelemetry的TracerProvider,之后带ctx的方法都会从ctx创建span,传nil取消
+func (c *Client) SetTracerProvider(tp trace.TracerProvider) {
+	if tp == nil {
+		c.tracer = nil
+		return
+	}
+	c.tracer = tp.Tracer(tracerName)
+}
+
+// startSpan 从ctx创建操作span,返回的函数在操作结束时调用,记录错误并结束span,未设置TracerProvider时不做任何操作
+func (c *Client) startSpan(ctx context.Context, op, database, collection string) (context.Context, func(err *error)) {
+	if c.tracer == nil {
+		return ctx, func(*error) {}
+	}
+	name := op
+	if collection != "" {
+		name += " " + database + "." + collection
+	} else if database != "" {
+		name += " " + database
+	}
+	ctx, span := c.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "mongodb"),
+			attribute.String("db.name", database),
+			attribute.String("db.mongodb.collection", collection),
+			attribute.String("db.operation", op),
+		),
+	)
+	return ctx, func(err *error) {
+		if *err != nil {
+			span.RecordError(*err)
+			span.SetStatus(codes.Error, (*err).Error())
+		}
+		span.End()
+	}
+}
@@ -0,0 +1,103 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// testTracer 设置记录到内存的TracerProvider,返回记录器
+func testTracer(c *Client) *tracetest.SpanRecorder {
+	rec := tracetest.NewSpanRecorder()
+	c.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(rec)))
+	return rec
+}
+
+// checkSpan 检查span的名称、类型和db.*属性
+func checkSpan(t *testing.T, span sdktrace.ReadOnlySpan, op, database, collection string) {
+	t.Helper()
+	if want := op + " " + database + "." + collection; span.Name() != want {
+		t.Errorf("span name = %q, want %q", span.Name(), want)
+	}
+	if span.SpanKind() != trace.SpanKindClient {
+		t.Errorf("span kind = %v, want client", span.SpanKind())
+	}
+	attrs := map[attribute.Key]string{}
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value.AsString()
+	}
+	for key, want := range map[attribute.Key]string{
+		"db.system":             "mongodb",
+		"db.name":               database,
+		"db.mongodb.collection": collection,
+		"db.operation":          op,
+	} {
+		if attrs[key] != want {
+			t.Errorf("%s = %q, want %q", key, attrs[key], want)
+		}
+	}
+}
+
+func TestTracingError(t *testing.T) {
+	c := &Client{}
+	rec := testTracer(c)
+	var row M
+	if err := c.GetRowContext(context.Background(), "db", "users", M{}, nil, &row); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("GetRowContext = %v, want ErrNotConnected", err)
+	}
+	spans := rec.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("got %d spans, want 1", len(spans))
+	}
+	checkSpan(t, spans[0], "GetRowContext", "db", "users")
+	if status := spans[0].Status(); status.Code != codes.Error || status.Description != ErrNotConnected.Error() {
+		t.Errorf("status = %+v, want error", status)
+	}
+	if len(spans[0].Events()) != 1 || spans[0].Events()[0].Name != "exception" {
+		t.Errorf("events = %+v, want the recorded error", spans[0].Events())
+	}
+
+	c.SetTracerProvider(nil)
+	if err := c.GetRowContext(context.Background(), "db", "users", M{}, nil, &row); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("GetRowContext without tracer = %v", err)
+	}
+	if len(rec.Ended()) != 1 {
+		t.Fatal("span recorded after SetTracerProvider(nil)")
+	}
+}
+
+func TestTracingContextCalls(t *testing.T) {
+	c := testClient(t)
+	coll := testCollection(t, c, "tracing")
+	rec := testTracer(c)
+	defer c.SetTracerProvider(nil)
+	ctx := context.Background()
+	if err := c.InsertContext(ctx, testDB, coll, M{"n": 1}); err != nil {
+		t.Fatal(err)
+	}
+	var row M
+	if err := c.GetRowContext(ctx, testDB, coll, M{"n": 1}, nil, &row); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.GetRowContext(ctx, testDB, coll, M{"n": 2}, nil, &row); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetRowContext missing = %v, want ErrNotFound", err)
+	}
+	spans := rec.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("got %d spans, want 3", len(spans))
+	}
+	for i, op := range []string{"InsertContext", "GetRowContext", "GetRowContext"} {
+		checkSpan(t, spans[i], op, testDB, coll)
+	}
+	for i, want := range []codes.Code{codes.Unset, codes.Unset, codes.Error} {
+		if got := spans[i].Status().Code; got != want {
+			t.Errorf("span %d status = %v, want %v", i, got, want)
+		}
+	}
+}
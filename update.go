@@ -0,0 +1,47 @@
+package mongo
+
+// Set 返回$set更新操作
+func Set(fields M) M {
+	return M{"$set": fields}
+}
+
+// Inc 返回$inc更新操作
+func Inc(fields M) M {
+	return M{"$inc": fields}
+}
+
+// Push 返回$push更新操作,多个值时使用$each
+func Push(field string, values ...interface{}) M {
+	if len(values) == 1 {
+		return M{"$push": M{field: values[0]}}
+	}
+	return M{"$push": M{field: M{"$each": values}}}
+}
+
+// Pull 返回$pull更新操作
+func Pull(field string, value interface{}) M {
+	return M{"$pull": M{field: value}}
+}
+
+// Merge 合并多个更新操作,同一操作符的字段合并到一起,如Merge(Set(M{"a": 1}), Inc(M{"n": 1}))
+func Merge(ops ...M) M {
+	update := M{}
+	for _, op := range ops {
+		for key, value := range op {
+			fields, ok := value.(M)
+			if !ok {
+				update[key] = value
+				continue
+			}
+			merged, ok := update[key].(M)
+			if !ok {
+				merged = M{}
+				update[key] = merged
+			}
+			for field, v := range fields {
+				merged[field] = v
+			}
+		}
+	}
+	return update
+}
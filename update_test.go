@@ -0,0 +1,34 @@
+package mongo
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUpdateBuilders(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		got  M
+		want M
+	}{
+		{"Set", Set(M{"a": 1}), M{"$set": M{"a": 1}}},
+		{"Inc", Inc(M{"n": -1}), M{"$inc": M{"n": -1}}},
+		{"Push", Push("tags", "a"), M{"$push": M{"tags": "a"}}},
+		{"PushEach", Push("tags", "a", "b"), M{"$push": M{"tags": M{"$each": []interface{}{"a", "b"}}}}},
+		{"Pull", Pull("tags", "a"), M{"$pull": M{"tags": "a"}}},
+		{"Merge", Merge(Set(M{"a": 1}), Inc(M{"n": 1}), Set(M{"b": 2})), M{"$set": M{"a": 1, "b": 2}, "$inc": M{"n": 1}}},
+		{"MergeEmpty", Merge(), M{}},
+	} {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s = %#v, want %#v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestMergeDoesNotModifyOps(t *testing.T) {
+	first := Set(M{"a": 1})
+	Merge(first, Set(M{"b": 2}))
+	if want := (M{"$set": M{"a": 1}}); !reflect.DeepEqual(first, want) {
+		t.Fatalf("first = %#v, want %#v", first, want)
+	}
+}
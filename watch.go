@@ -0,0 +1,103 @@
+package mongo
+
+import (
+	"time"
+
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+// ChangeEvent 变更流事件
+type ChangeEvent struct {
+	ID                       M      `bson:"_id"`                      //恢复令牌,保存后可作为Watch的resumeToken继续监听
+	OperationType            string `bson:"operationType"`            //操作类型,如insert、update、replace、delete
+	DocumentKey              M      `bson:"documentKey"`              //变更数据的_id
+	FullDocument             M      `bson:"fullDocument"`             //变更后的完整数据,delete时为空
+	FullDocumentBeforeChange M      `bson:"fullDocumentBeforeChange"` //变更前的完整数据,只有WatchOpt设置了FullDocumentBeforeChange时返回
+}
+
+// WatchOptions 变更流选项
+type WatchOptions struct {
+	//返回变更前的数据,可选"whenAvailable"或"required",需要MongoDB 6.0及以上
+	//并通过collMod为集合开启changeStreamPreAndPostImages,"required"时没有变更前数据会报错
+	FullDocumentBeforeChange string
+}
+
+// Watch 监听集合的变更流,每个事件调用一次handler,需要副本集或分片集群
+// resumeToken不为空时从该令牌之后继续监听,handler返回错误时停止监听并返回该错误
+func (c *Client) Watch(database, collection string, pipeline []M, resumeToken M, handler func(ChangeEvent) error) (err error) {
+	defer c.observe(&err, "Watch", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	opts := mgo.ChangeStreamOptions{FullDocument: mgo.UpdateLookup}
+	if len(resumeToken) > 0 {
+		data, err := bson.Marshal(resumeToken)
+		if err != nil {
+			return err
+		}
+		opts.ResumeAfter = &bson.Raw{Kind: 0x03, Data: data}
+	}
+	if pipeline == nil {
+		pipeline = []M{}
+	}
+	stream, err := conn.Watch(pipeline, opts)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+	for {
+		var event ChangeEvent
+		for stream.Next(&event) {
+			if err := handler(event); err != nil {
+				return err
+			}
+			event = ChangeEvent{}
+		}
+		if err := stream.Err(); err != nil {
+			return err
+		}
+	}
+}
+
+// WatchOpt 按选项监听集合的变更流,FullDocumentBeforeChange为空时与Watch相同
+// mgo的变更流不支持fullDocumentBeforeChange,因此通过聚合游标读取,临时网络错误时从最后一个事件的恢复令牌重新监听
+func (c *Client) WatchOpt(database, collection string, pipeline []M, resumeToken M, opts WatchOptions, handler func(ChangeEvent) error) (err error) {
+	if opts.FullDocumentBeforeChange == "" {
+		return c.Watch(database, collection, pipeline, resumeToken, handler)
+	}
+	defer c.observe(&err, "WatchOpt", database, collection, time.Now())
+	if err := c.ready(); err != nil {
+		return err
+	}
+	session := c.session.Copy()
+	defer session.Close()
+	conn := session.DB(database).C(collection)
+	for {
+		stage := M{"fullDocument": mgo.UpdateLookup, "fullDocumentBeforeChange": opts.FullDocumentBeforeChange}
+		if len(resumeToken) > 0 {
+			stage["resumeAfter"] = resumeToken
+		}
+		stages := make([]M, 0, len(pipeline)+1)
+		stages = append(stages, M{"$changeStream": stage})
+		stages = append(stages, pipeline...)
+		iter := conn.Pipe(stages).Iter()
+		var event ChangeEvent
+		for iter.Next(&event) {
+			resumeToken = event.ID
+			if err := handler(event); err != nil {
+				iter.Close()
+				return err
+			}
+			event = ChangeEvent{}
+		}
+		err := iter.Close()
+		if !isTransient(err) {
+			return err
+		}
+		session.Refresh()
+	}
+}